@@ -0,0 +1,82 @@
+package convert
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// popularCurrencies are the currencies converted to by the all target,
+// since every currency would take a call to the currency service each
+var popularCurrencies = []string{"USD", "EUR", "JPY", "GBP", "CNY", "CAD", "AUD", "CHF"}
+
+// SetPopularCurrencies sets the currency codes converted to by the all target.
+// Codes that aren't currencies are logged once the currencies have loaded
+func SetPopularCurrencies(codes []string) {
+	var popular []string
+	for _, code := range codes {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			popular = append(popular, code)
+		}
+	}
+	popularCurrencies = popular
+	go warnUnknownCurrencies(popular)
+}
+
+func warnUnknownCurrencies(codes []string) {
+	// This runs in the background, so it can wait for as long as the first load takes
+	<-startCurrencyLoader()
+	if state, _ := CurrencyStatus(); state != CurrenciesReady {
+		return
+	}
+	for _, code := range codes {
+		if u, ok := LookupUnit(code); !ok || u.Dimension() != UnitDimensionCurrency {
+			slog.Warn("Unknown popular currency", "code", code)
+		}
+	}
+}
+
+// ConvertToAll converts a value to every unit of its dimension, from smallest to largest value
+func ConvertToAll(v UnitVal) (string, error) {
+	var units []UnitType
+	if v.Unit().Dimension() == UnitDimensionCurrency {
+		for _, code := range popularCurrencies {
+			if u, ok := LookupUnit(code); ok {
+				units = append(units, u)
+			}
+		}
+	} else {
+		units = unitsOf(v.Unit().Dimension())
+	}
+
+	var vals []UnitVal
+	for _, u := range units {
+		if u == v.Unit() || u == UnitType(FootInch) {
+			continue
+		}
+		val, err := v.Convert(u)
+		if err != nil {
+			slog.Debug("skipping unit", "unit", u, "err", err)
+			continue
+		}
+		vals = append(vals, val)
+	}
+	if len(vals) == 0 {
+		return "", fmt.Errorf("No other units to convert %s to", v.Unit())
+	}
+
+	sort.SliceStable(vals, func(i, j int) bool {
+		return magnitude(vals[i]) < magnitude(vals[j])
+	})
+
+	var reply strings.Builder
+	reply.WriteString(v.String())
+	reply.WriteString(" =\n```\n")
+	for _, val := range vals {
+		reply.WriteString(val.String())
+		reply.WriteString("\n")
+	}
+	reply.WriteString("```")
+	return reply.String(), nil
+}
@@ -0,0 +1,84 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrorDivideByZero occurs when a value is divided by zero
+var ErrorDivideByZero = errors.New("Can't divide by zero")
+
+// ErrorIncompatible occurs when an operation combines values of different dimensions,
+// or when B is nil, when it has no meaning for values of A
+type ErrorIncompatible struct {
+	Op   string
+	A, B UnitType
+}
+
+func (err ErrorIncompatible) Error() string {
+	if err.B == nil {
+		return fmt.Sprintf("Can't %s a %s", err.Op, err.A.Dimension())
+	}
+	return fmt.Sprintf("Can't %s %s and %s", err.Op, err.A.String(), err.B.String())
+}
+
+// reconcile converts b to the unit of a, checking that their dimensions match
+func reconcile(op string, a, b UnitVal) (UnitVal, error) {
+	if a.Unit().Dimension() != b.Unit().Dimension() {
+		return nil, ErrorIncompatible{op, a.Unit(), b.Unit()}
+	}
+	return b.Convert(a.Unit())
+}
+
+// combine adds sign*b to a in the unit of a, converting b to that unit first.
+// Values in the same offset unit add as numbers, so 20 °C + 5 °C is 25 °C. Values on different temperature scales
+// can't be combined, since converting b to the unit of a would treat it as a temperature rather than a change
+// in temperature, e.g. 10 °F as -12.2 °C. Uncertainties of the operands are combined in quadrature
+func combine(op string, a, b UnitVal, sign float64) (UnitVal, error) {
+	if a.Unit() != b.Unit() && (hasOffset(a.Unit()) || hasOffset(b.Unit())) {
+		return nil, ErrorIncompatible{op, a.Unit(), b.Unit()}
+	}
+	bc, err := reconcile(op, a, b)
+	if err != nil {
+		return nil, err
+	}
+	val := a.Unit().FromFloat(magnitude(a) + sign*magnitude(bc))
+	if d := math.Hypot(uncertainty(a), uncertainty(bc)); d != 0 {
+		return UncertainVal{val, d}, nil
+	}
+	return val, nil
+}
+
+// scale multiplies a value by an exact factor in its own unit, keeping its relative uncertainty
+func scale(a UnitVal, k float64) UnitVal {
+	val := a.Unit().FromFloat(magnitude(a) * k)
+	if d := uncertainty(a); d != 0 {
+		return UncertainVal{val, math.Abs(d * k)}
+	}
+	return val
+}
+
+// divide divides a value by an exact factor in its own unit, keeping its relative uncertainty
+func divide(a UnitVal, k float64) (UnitVal, error) {
+	if k == 0 {
+		return nil, ErrorDivideByZero
+	}
+	return scale(a, 1/k), nil
+}
+
+// compare compares the central values of a and b
+func compare(a, b UnitVal) (int, error) {
+	bc, err := reconcile("compare", a, b)
+	if err != nil {
+		return 0, err
+	}
+	switch x, y := magnitude(a), magnitude(bc); {
+	case x < y:
+		return -1, nil
+	case x > y:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
@@ -0,0 +1,148 @@
+package convert
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    UnitVal
+		want    float64
+		wantErr bool
+	}{
+		{"same unit", Meter.FromFloat(1), Meter.FromFloat(2), 2, false},
+		{"length", Meter.FromFloat(1), Kilometer.FromFloat(2), 2000, false},
+		{"mass", Kilogram.FromFloat(1), Gram.FromFloat(500), 0.5, false},
+		{"temperature", Celsius.FromFloat(0), Kelvin.FromFloat(273.15), 0, false},
+		{"duration", Minute.FromFloat(1), Hour.FromFloat(1), 60, false},
+		{"length and mass", Meter.FromFloat(1), Kilogram.FromFloat(1), 0, true},
+		{"mass and duration", Gram.FromFloat(1), Second.FromFloat(1), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reconcile("add", tt.a, tt.b)
+			if tt.wantErr {
+				var incompatible ErrorIncompatible
+				if !errors.As(err, &incompatible) {
+					t.Fatalf("reconcile() error = %v, want ErrorIncompatible", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reconcile() error = %v", err)
+			}
+			if got.Unit() != tt.a.Unit() {
+				t.Errorf("reconcile() unit = %s, want %s", got.Unit(), tt.a.Unit())
+			}
+			if !approx(magnitude(got), tt.want) {
+				t.Errorf("reconcile() = %v, want %v", magnitude(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      UnitVal
+		sign      float64
+		want      float64
+		wantDelta float64
+		wantErr   bool
+	}{
+		{"add lengths", Meter.FromFloat(1), Centimeter.FromFloat(50), 1, 1.5, 0, false},
+		{"subtract masses", Kilogram.FromFloat(2), Gram.FromFloat(500), -1, 1.5, 0, false},
+		{"add to offset unit", Celsius.FromFloat(20), Celsius.FromFloat(5), 1, 25, 0, false},
+		{"add durations", Hour.FromFloat(1), Minute.FromFloat(30), 1, 1.5, 0, false},
+		{"uncertainties in quadrature", UncertainVal{Meter.FromFloat(1), 0.3}, UncertainVal{Meter.FromFloat(2), 0.4}, 1, 3, 0.5, false},
+		{"uncertainty converted", Meter.FromFloat(1), UncertainVal{Centimeter.FromFloat(10), 1}, -1, 0.9, 0.01, false},
+		{"length and mass", Meter.FromFloat(1), Kilogram.FromFloat(1), 1, 0, 0, true},
+		{"temperature and duration", Celsius.FromFloat(1), Second.FromFloat(1), -1, 0, 0, true},
+		{"temperature scales", Celsius.FromFloat(20), Fahrenheit.FromFloat(10), 1, 0, 0, true},
+		{"offset and absolute temperature", Celsius.FromFloat(20), Kelvin.FromFloat(10), 1, 0, 0, true},
+		{"absolute temperatures", Kelvin.FromFloat(300), Kelvin.FromFloat(10), -1, 290, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := combine("add", tt.a, tt.b, tt.sign)
+			if tt.wantErr {
+				var incompatible ErrorIncompatible
+				if !errors.As(err, &incompatible) {
+					t.Fatalf("combine() error = %v, want ErrorIncompatible", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("combine() error = %v", err)
+			}
+			if got.Unit() != tt.a.Unit() {
+				t.Errorf("combine() unit = %s, want %s", got.Unit(), tt.a.Unit())
+			}
+			if !approx(magnitude(got), tt.want) {
+				t.Errorf("combine() = %v, want %v", magnitude(got), tt.want)
+			}
+			if !approx(uncertainty(got), tt.wantDelta) {
+				t.Errorf("combine() uncertainty = %v, want %v", uncertainty(got), tt.wantDelta)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    UnitVal
+		want    int
+		wantErr bool
+	}{
+		{"shorter", Centimeter.FromFloat(99), Meter.FromFloat(1), -1, false},
+		{"longer", Foot.FromFloat(1), Inch.FromFloat(11), 1, false},
+		{"equal", Kilogram.FromFloat(1), Gram.FromFloat(1000), 0, false},
+		{"offset units", Fahrenheit.FromFloat(32), Celsius.FromFloat(0), 0, false},
+		{"uncertainty ignored", UncertainVal{Meter.FromFloat(1), 5}, Meter.FromFloat(2), -1, false},
+		{"length and duration", Meter.FromFloat(1), Second.FromFloat(1), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compare(tt.a, tt.b)
+			if tt.wantErr {
+				var incompatible ErrorIncompatible
+				if !errors.As(err, &incompatible) {
+					t.Fatalf("compare() error = %v, want ErrorIncompatible", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compare() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("compare() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDivideByZero(t *testing.T) {
+	values := []UnitVal{
+		Meter.FromFloat(1),
+		Kilogram.FromFloat(1),
+		FootInch.FromFloat(1),
+		UncertainVal{Meter.FromFloat(1), 0.1},
+		Quantity{1, Newton},
+	}
+	for _, v := range values {
+		if _, err := v.Div(0); !errors.Is(err, ErrorDivideByZero) {
+			t.Errorf("%s.Div(0) error = %v, want ErrorDivideByZero", v, err)
+		}
+	}
+	if _, err := Divide(Meter.FromFloat(1), Second.FromFloat(0)); !errors.Is(err, ErrorDivideByZero) {
+		t.Errorf("Divide() error = %v, want ErrorDivideByZero", err)
+	}
+}
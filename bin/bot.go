@@ -4,11 +4,15 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
+	"unicode"
 
 	convert "unit-bot"
 
@@ -30,7 +34,63 @@ func init() {
 }
 
 func main() {
-	convert.SetCurrencyApiKey(os.Getenv("CURRENCY_API_KEY"))
+	if err := addCryptoAssets(os.Getenv("CRYPTO_ASSETS")); err != nil {
+		slog.Error("Invalid crypto assets", "err", err)
+		os.Exit(1)
+	}
+	providers, err := rateProviders(os.Getenv("RATE_PROVIDERS"))
+	if err != nil {
+		slog.Error("Invalid exchange rate providers", "err", err)
+		os.Exit(1)
+	}
+	convert.SetRateProviders(providers...)
+	historyProviders, err := historicalRateProviders(os.Getenv("RATE_HISTORY_PROVIDERS"))
+	if err != nil {
+		slog.Error("Invalid historical exchange rate providers", "err", err)
+		os.Exit(1)
+	}
+	convert.SetHistoricalRateProviders(historyProviders...)
+	if base, ok := os.LookupEnv("RATES_BASE"); ok {
+		convert.SetRateBase(base)
+	}
+	if path, ok := os.LookupEnv("RATES_STORE"); ok {
+		if err := convert.LoadRateStore(path); err != nil {
+			slog.Error("Unable to load stored rates", "path", path, "err", err)
+		}
+	}
+	refresh := time.Hour
+	if interval, ok := os.LookupEnv("RATES_REFRESH"); ok {
+		if refresh, err = time.ParseDuration(interval); err != nil {
+			slog.Error("Invalid rate refresh interval", "interval", interval, "err", err)
+			os.Exit(1)
+		}
+	}
+	if err := convert.RefreshRates(refresh); err != nil {
+		slog.Error("Invalid rate refresh interval", "interval", refresh, "err", err)
+		os.Exit(1)
+	}
+	convert.LoadCurrencies()
+	if path, ok := os.LookupEnv("SETTINGS_FILE"); ok {
+		if err := convert.LoadSettings(path); err != nil {
+			slog.Error("Unable to load settings", "path", path, "err", err)
+		}
+	}
+	display := "code"
+	if d, ok := os.LookupEnv("CURRENCY_DISPLAY"); ok {
+		display = d
+	}
+	if err := convert.SetCurrencyFormat(display, os.Getenv("CURRENCY_LOCALE")); err != nil {
+		slog.Error("Invalid currency format", "err", err)
+		os.Exit(1)
+	}
+	if path, ok := os.LookupEnv("CPI_FILE"); ok {
+		if err := convert.LoadCPI(path); err != nil {
+			slog.Error("Unable to load price indices", "path", path, "err", err)
+		}
+	}
+	if popular, ok := os.LookupEnv("POPULAR_CURRENCIES"); ok {
+		convert.SetPopularCurrencies(strings.Split(popular, ","))
+	}
 
 	discordToken, ok := os.LookupEnv("UNIT_BOT_TOKEN")
 	if !ok {
@@ -48,9 +108,248 @@ func main() {
 	signal.Notify(sc, os.Interrupt, syscall.SIGTERM)
 	<-sc
 	slog.Info("Stopping Unit Bot")
+	convert.FlushRateStore()
+}
+
+// rateProviders makes the exchange rate providers in a comma separated list, in the order to try them.
+// By default free.currconv.com is used if there is an API key for it, falling back to the ECB,
+// with CoinGecko for crypto
+func rateProviders(names string) ([]convert.RateProvider, error) {
+	if names == "" {
+		names = "ecb,coingecko"
+		if os.Getenv("CURRENCY_API_KEY") != "" {
+			names = "currconv,ecb,coingecko"
+		}
+	}
+
+	var providers []convert.RateProvider
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "ecb":
+			providers = append(providers, convert.ECBProvider{})
+		case "ecbhistory":
+			providers = append(providers, &convert.ECBHistoryProvider{Path: os.Getenv("ECB_HISTORY_FILE")})
+		case "openexchangerates":
+			providers = append(providers, convert.OpenExchangeRatesProvider{AppID: os.Getenv("OPENEXCHANGERATES_APP_ID")})
+		case "currconv":
+			providers = append(providers, convert.CurrConvProvider{APIKey: os.Getenv("CURRENCY_API_KEY")})
+		case "coingecko":
+			providers = append(providers, convert.CoinGeckoProvider{APIKey: os.Getenv("COINGECKO_API_KEY")})
+		case "static":
+			providers = append(providers, convert.StaticProvider{Path: os.Getenv("RATES_FILE")})
+		default:
+			return nil, fmt.Errorf("unknown exchange rate provider %q", name)
+		}
+	}
+	return providers, nil
+}
+
+// historicalRateProviders makes the providers of past exchange rates in a comma separated list, in the order to try them.
+// By default the ECB's history is used
+func historicalRateProviders(names string) ([]convert.HistoricalRateProvider, error) {
+	if names == "" {
+		names = "ecbhistory"
+	}
+
+	var providers []convert.HistoricalRateProvider
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "ecbhistory":
+			providers = append(providers, &convert.ECBHistoryProvider{Path: os.Getenv("ECB_HISTORY_FILE")})
+		case "none":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unknown historical exchange rate provider %q", name)
+		}
+	}
+	return providers, nil
+}
+
+// addCryptoAssets adds the crypto assets in a comma separated list of CODE=id:decimals,
+// where id is the asset's CoinGecko ID, e.g. SOL=solana:9,DOGE=dogecoin:8
+func addCryptoAssets(assets string) error {
+	if assets == "" {
+		return nil
+	}
+	for _, asset := range strings.Split(assets, ",") {
+		code, rest, ok := strings.Cut(strings.TrimSpace(asset), "=")
+		if !ok {
+			return fmt.Errorf("expected CODE=id:decimals, got %q", asset)
+		}
+		id, places, _ := strings.Cut(rest, ":")
+		decimals := 8
+		if places != "" {
+			var err error
+			if decimals, err = strconv.Atoi(places); err != nil {
+				return fmt.Errorf("invalid decimals in %q: %w", asset, err)
+			}
+		}
+		convert.AddCryptoAsset(convert.CryptoAsset{Code: code, Name: code, ID: id, Decimals: decimals})
+	}
+	return nil
+}
+
+// messageCommand replies to the arguments of a command sent as a message
+type messageCommand func(discord *discordgo.Session, m *discordgo.MessageCreate, args string) string
+
+// messageCommands are the commands that can be sent as a message, by their first word
+var messageCommands = map[string]messageCommand{
+	"!conv":          processInScope,
+	"!const":         unscoped(convert.Constant),
+	"!units":         unscoped(convert.Units),
+	"!unit":          unscoped(convert.UnitInfo),
+	"!tz":            setHomeZone,
+	"!tzhome":        setGuildHomeZone,
+	"!tzdefault":     setZoneDefault,
+	"!symbol":        setSymbol,
+	"!symboldefault": setSymbolDefault,
+	"!until":         untilInScope,
+}
+
+// unscoped makes a message command that doesn't depend on where it was sent
+func unscoped(f func(string) string) messageCommand {
+	return func(_ *discordgo.Session, _ *discordgo.MessageCreate, args string) string {
+		return f(args)
+	}
+}
+
+func processInScope(_ *discordgo.Session, m *discordgo.MessageCreate, args string) string {
+	return convert.ProcessIn(convert.Scope{GuildID: m.GuildID, UserID: m.Author.ID}, args)
+}
+
+func untilInScope(_ *discordgo.Session, m *discordgo.MessageCreate, args string) string {
+	return convert.Until(convert.Scope{GuildID: m.GuildID, UserID: m.Author.ID}, args)
+}
+
+// setHomeZone sets the time zone relative dates like today are in for the user, e.g. !tz Europe/Paris
+func setHomeZone(_ *discordgo.Session, m *discordgo.MessageCreate, args string) string {
+	fields := strings.Fields(args)
+	if len(fields) > 1 {
+		return "Usage: !tz [time zone], or leave out the time zone to reset it"
+	}
+	if len(fields) == 0 {
+		if err := convert.SetUserHomeZone(m.Author.ID, ""); err != nil {
+			return err.Error()
+		}
+		return "Dates like today are back to the server's time zone for you"
+	}
+	if err := convert.SetUserHomeZone(m.Author.ID, fields[0]); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("Dates like today are now in %s for you", fields[0])
+}
+
+// setGuildHomeZone sets the time zone relative dates like today are in for a guild, e.g. !tzhome Europe/Paris.
+// Only members who can manage the server can change it
+func setGuildHomeZone(discord *discordgo.Session, m *discordgo.MessageCreate, args string) string {
+	fields := strings.Fields(args)
+	if len(fields) > 1 {
+		return "Usage: !tzhome [time zone], or leave out the time zone to reset it to UTC"
+	}
+	if m.GuildID == "" {
+		return "The server time zone can only be set in a server, use !tz to set your own"
+	}
+	perms, err := discord.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil {
+		slog.Warn("Unable to get permissions", "err", err)
+		return "Unable to check your permissions"
+	}
+	if perms&discordgo.PermissionManageServer == 0 {
+		return "You need the Manage Server permission to set the server time zone"
+	}
+
+	if len(fields) == 0 {
+		if err := convert.SetGuildHomeZone(m.GuildID, ""); err != nil {
+			return err.Error()
+		}
+		return "Dates like today are back to UTC in this server"
+	}
+	if err := convert.SetGuildHomeZone(m.GuildID, fields[0]); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("Dates like today are now in %s in this server", fields[0])
+}
+
+// setZoneDefault sets which time zone an abbreviation means in a guild, e.g. !tzdefault IST Asia/Jerusalem.
+// Only members who can manage the server can change it
+func setZoneDefault(discord *discordgo.Session, m *discordgo.MessageCreate, args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "Usage: !tzdefault [abbreviation] [time zone], or leave out the time zone to reset it"
+	}
+	if m.GuildID == "" {
+		return "Time zone defaults can only be set in a server"
+	}
+	perms, err := discord.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil {
+		slog.Warn("Unable to get permissions", "err", err)
+		return "Unable to check your permissions"
+	}
+	if perms&discordgo.PermissionManageServer == 0 {
+		return "You need the Manage Server permission to set time zone defaults"
+	}
+
+	abbr := strings.ToUpper(fields[0])
+	if len(fields) == 1 {
+		if err := convert.SetGuildZone(m.GuildID, abbr, ""); err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("%s is back to its default time zone", abbr)
+	}
+	if err := convert.SetGuildZone(m.GuildID, abbr, fields[1]); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s now means %s in this server", abbr, fields[1])
+}
+
+// setSymbol sets which currency an ambiguous symbol means for the user, e.g. !symbol $ CAD
+func setSymbol(_ *discordgo.Session, m *discordgo.MessageCreate, args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "Usage: !symbol [symbol] [currency], or leave out the currency to reset it"
+	}
+	if len(fields) == 1 {
+		if err := convert.SetUserSymbol(m.Author.ID, fields[0], ""); err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("%s is back to its default currency for you", fields[0])
+	}
+	if err := convert.SetUserSymbol(m.Author.ID, fields[0], fields[1]); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s now means %s for you", fields[0], strings.ToUpper(fields[1]))
 }
 
-const convertPrefix = "!conv "
+// setSymbolDefault sets which currency an ambiguous symbol means in a guild, e.g. !symboldefault $ AUD.
+// Only members who can manage the server can change it
+func setSymbolDefault(discord *discordgo.Session, m *discordgo.MessageCreate, args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "Usage: !symboldefault [symbol] [currency], or leave out the currency to reset it"
+	}
+	if m.GuildID == "" {
+		return "Symbol defaults can only be set in a server, use !symbol to set your own"
+	}
+	perms, err := discord.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil {
+		slog.Warn("Unable to get permissions", "err", err)
+		return "Unable to check your permissions"
+	}
+	if perms&discordgo.PermissionManageServer == 0 {
+		return "You need the Manage Server permission to set symbol defaults"
+	}
+
+	if len(fields) == 1 {
+		if err := convert.SetGuildSymbol(m.GuildID, fields[0], ""); err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("%s is back to its default currency", fields[0])
+	}
+	if err := convert.SetGuildSymbol(m.GuildID, fields[0], fields[1]); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s now means %s in this server", fields[0], strings.ToUpper(fields[1]))
+}
 
 func startDiscord(discordToken string) func() {
 	discordClient, _ := discordgo.New("Bot " + discordToken)
@@ -79,7 +378,7 @@ func startDiscord(discordToken string) func() {
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
 				Name:        "from-value",
-				Description: "value and unit to convert from",
+				Description: "values and units to convert from, separated by commas",
 				Required:    true,
 			},
 			{
@@ -89,15 +388,94 @@ func startDiscord(discordToken string) func() {
 				Required:     true,
 				Autocomplete: true,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "explain",
+				Description: "show the factors used in the conversion",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "date",
+				Description: "use the exchange rates of a past date, YYYY-MM-DD",
+			},
 		},
 	}, handleConvertInteraction)
 
+	createCommand(discordClient, &discordgo.ApplicationCommand{
+		Name:        "compare",
+		Description: "orders values of the same kind",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "values",
+				Description: "comma separated values and units to compare",
+				Required:    true,
+			},
+		},
+	}, handleCompareInteraction)
+
+	var dimensionChoices []*discordgo.ApplicationCommandOptionChoice
+	for _, dim := range []convert.UnitDimension{
+		convert.UnitDimensionLength,
+		convert.UnitDimensionMass,
+		convert.UnitDimensionSpeed,
+		convert.UnitDimensionDuration,
+		convert.UnitDimensionTemperature,
+		convert.UnitDimensionVolume,
+		convert.UnitDimensionCurrency,
+		convert.UnitDimensionDate,
+		convert.UnitDimensionForce,
+		convert.UnitDimensionEnergy,
+		convert.UnitDimensionPower,
+		convert.UnitDimensionPressure,
+		convert.UnitDimensionFrequency,
+		convert.UnitDimensionCurrent,
+		convert.UnitDimensionAmount,
+		convert.UnitDimensionLuminousIntensity,
+	} {
+		dimensionChoices = append(dimensionChoices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  dim.String(),
+			Value: dim.String(),
+		})
+	}
+
+	createCommand(discordClient, &discordgo.ApplicationCommand{
+		Name:        "units",
+		Description: "lists the units that can be converted",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "dimension",
+				Description: "kind of unit to list",
+				Choices:     dimensionChoices,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "page",
+				Description: "page of units to show",
+			},
+		},
+	}, handleUnitsInteraction)
+
+	createCommand(discordClient, &discordgo.ApplicationCommand{
+		Name:        "timestamp",
+		Description: "makes timestamps that show in everyone's local time",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "time",
+				Description: "date, time and time zone, e.g. 2024-03-01 8pm PST",
+				Required:    true,
+			},
+		},
+	}, handleTimestampInteraction)
+
 	discordClient.AddHandler(func(discord *discordgo.Session, i *discordgo.InteractionCreate) {
 		cmd := i.ApplicationCommandData().Name
 		if handler, ok := commandHandlerMap[cmd]; ok {
 			handler(discord, i)
 		} else {
-			slog.Warn("unknown command:", cmd)
+			slog.Warn("unknown command", "Name", cmd)
 		}
 	})
 
@@ -150,19 +528,29 @@ func cleanupCommands(discord *discordgo.Session) error {
 func handleConvertInteraction(discord *discordgo.Session, i *discordgo.InteractionCreate) {
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
-		var fromValue, toUnit string
+		var fromValue, toUnit, date string
+		var explain bool
 		for _, o := range i.ApplicationCommandData().Options {
 			switch o.Name {
 			case "from-value":
 				fromValue = o.StringValue()
 			case "to-unit":
 				toUnit = o.StringValue()
+			case "explain":
+				explain = o.BoolValue()
+			case "date":
+				date = o.StringValue()
 			default:
 				slog.Warn("unexpected command option", "Option", o.Name)
 			}
 		}
 
-		convertResult := convert.Convert(fromValue, toUnit)
+		var convertResult string
+		if explain {
+			convertResult = convert.ExplainOn(interactionScope(i), fromValue, toUnit, date)
+		} else {
+			convertResult = convert.ConvertOn(interactionScope(i), fromValue, toUnit, date)
+		}
 
 		discord.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -182,12 +570,13 @@ func handleConvertInteraction(discord *discordgo.Session, i *discordgo.Interacti
 				fromValue = o.StringValue()
 			case "to-unit":
 				toUnit = o.StringValue()
+			case "explain", "date":
 			default:
 				slog.Warn("unexpected command option", "Option", o.Name)
 			}
 		}
 
-		autocompletes := convert.Autocomplete(fromValue, toUnit)
+		autocompletes := convert.Autocomplete(interactionScope(i), fromValue, toUnit)
 
 		slog.Info("Received autocomplete interaction",
 			"from", fromValue, "to", toUnit, "focused", focused, "autocompletes", autocompletes)
@@ -212,6 +601,92 @@ func handleConvertInteraction(discord *discordgo.Session, i *discordgo.Interacti
 
 }
 
+func handleCompareInteraction(discord *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		slog.Warn("unexpected interaction type", "Type", i.Type)
+		return
+	}
+
+	var values string
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "values":
+			values = o.StringValue()
+		default:
+			slog.Warn("unexpected command option", "Option", o.Name)
+		}
+	}
+
+	discord.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: convert.Compare(values),
+		},
+	})
+}
+
+func handleUnitsInteraction(discord *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		slog.Warn("unexpected interaction type", "Type", i.Type)
+		return
+	}
+
+	var dimension string
+	page := 1
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "dimension":
+			dimension = o.StringValue()
+		case "page":
+			page = int(o.IntValue())
+		default:
+			slog.Warn("unexpected command option", "Option", o.Name)
+		}
+	}
+
+	discord.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: convert.UnitsPage(dimension, page),
+		},
+	})
+}
+
+func handleTimestampInteraction(discord *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		slog.Warn("unexpected interaction type", "Type", i.Type)
+		return
+	}
+
+	var when string
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "time":
+			when = o.StringValue()
+		default:
+			slog.Warn("unexpected command option", "Option", o.Name)
+		}
+	}
+
+	discord.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: convert.Timestamp(interactionScope(i), when),
+		},
+	})
+}
+
+// interactionScope is the guild and user an interaction came from
+func interactionScope(i *discordgo.InteractionCreate) convert.Scope {
+	scope := convert.Scope{GuildID: i.GuildID}
+	if i.Member != nil && i.Member.User != nil {
+		scope.UserID = i.Member.User.ID
+	} else if i.User != nil {
+		scope.UserID = i.User.ID
+	}
+	return scope
+}
+
 func processMessage(discord *discordgo.Session, m *discordgo.MessageCreate) {
 	// Just in case
 	defer func() {
@@ -224,12 +699,17 @@ func processMessage(discord *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
-	message := m.Content
-	if !strings.HasPrefix(message, convertPrefix) {
+	// Commands can be followed by a newline as well as a space, e.g. on mobile
+	name, args := m.Content, ""
+	if i := strings.IndexFunc(m.Content, unicode.IsSpace); i >= 0 {
+		name, args = m.Content[:i], strings.TrimLeftFunc(m.Content[i:], unicode.IsSpace)
+	}
+	command, ok := messageCommands[name]
+	if !ok {
 		return
 	}
 
-	reply := convert.Process(strings.TrimPrefix(message, convertPrefix))
+	reply := command(discord, m, args)
 
 	_, err := discord.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
 		Content: reply,
@@ -0,0 +1,91 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	p "unit-bot/parser"
+)
+
+// calcCommand multiplies and divides values, e.g. 1 N_A * 18 g/mol
+type calcCommand struct {
+	first any
+	terms []calcTerm
+	to    string
+}
+
+// calcTerm is an operator followed by either a value with unit or a plain number
+type calcTerm struct {
+	op  string
+	val any
+}
+
+var (
+	mulOp     = p.Token(`[*×/÷]`)
+	operand   = p.First(fromExpr, p.Map(p.Float, func(f float64) any { return f }))
+	calcTerms = p.Many(p.Parse2(mulOp, operand, func(op string, v any) calcTerm { return calcTerm{op, v} }))
+	target    = p.Parse2(p.Atom(`to`), unitName, func(_ string, u string) string { return u })
+	calcExpr  = p.Parse3(fromExpr, calcTerms.Filter(func(ts []calcTerm) bool { return len(ts) > 0 }), target.Or(""), mapCalc)
+)
+
+func mapCalc(first any, terms []calcTerm, to string) calcCommand {
+	return calcCommand{first, terms, to}
+}
+
+func (cmd calcCommand) run(scope Scope) string {
+	acc, err := resolveValue(scope, cmd.first)
+	if err != nil {
+		return err.Error()
+	}
+
+	var expr strings.Builder
+	expr.WriteString(acc.String())
+	for _, t := range cmd.terms {
+		divide := t.op == "/" || t.op == "÷"
+		if divide {
+			expr.WriteString(" ÷ ")
+		} else {
+			expr.WriteString(" × ")
+		}
+
+		if k, ok := t.val.(float64); ok {
+			fmt.Fprintf(&expr, "%g", k)
+			if divide {
+				acc, err = acc.Div(k)
+			} else {
+				acc, err = acc.Mul(k)
+			}
+			if err != nil {
+				return err.Error()
+			}
+			continue
+		}
+
+		v, err := resolveValue(scope, t.val)
+		if err != nil {
+			return err.Error()
+		}
+		expr.WriteString(v.String())
+		if divide {
+			acc, err = Divide(acc, v)
+		} else {
+			acc, err = Multiply(acc, v)
+		}
+		if err != nil {
+			return err.Error()
+		}
+	}
+
+	if cmd.to != "" {
+		toUnit, ok := lookupUnitIn(scope, cmd.to)
+		if !ok {
+			return ErrorInvalidUnit{cmd.to}.Error()
+		}
+		acc, err = acc.Convert(toUnit)
+		if err != nil {
+			return err.Error()
+		}
+	}
+
+	return fmt.Sprintf("%s = %s", expr.String(), acc)
+}
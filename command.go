@@ -4,107 +4,176 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"unicode/utf8"
 
 	p "unit-bot/parser"
 )
 
 func Process(expr string) string {
-	cmd, _, ok := convertExpr([]byte(expr))
+	return ProcessIn(Scope{}, expr)
+}
+
+// ProcessIn runs a command using the defaults of the guild and user it was sent from
+func ProcessIn(scope Scope, expr string) string {
+	cmd, _, ok := processExpr([]byte(expr))
 	if !ok {
-		slog.Info("Invalid command: `%v` %v\n", expr, cmd)
+		slog.Info("Invalid command", "expr", expr, "values", cmd)
 		return "Usage: !conv [amount][from-unit] to [to-unit]"
 	}
 
-	var from UnitVal
-	switch uv := cmd.from.(type) {
-	case unparsedUnitVal:
-		fromUnit, ok := LookupUnit(uv.unit)
-		if !ok {
-			return fmt.Sprintf("Invalid unit %s", uv.unit)
-		}
+	return cmd.run(scope)
+}
+
+func (cmd command) run(scope Scope) string {
+	return convertAll(scope, cmd.from, cmd.to, cmd.on)
+}
 
-		from = fromUnit.FromFloat(uv.val)
+func Convert(from, to string) string {
+	return ConvertOn(Scope{}, from, to, "")
+}
 
-	case UnitVal:
-		from = uv
+// ConvertOn converts values using the defaults of the guild and user it was sent from,
+// and the exchange rates of a date, the latest rates if it's empty
+func ConvertOn(scope Scope, from, to, on string) string {
+	if cmd, ok := parseInflation(from + " to " + to); ok && on == "" {
+		return cmd.run(scope)
 	}
-
-	toUnit, ok := LookupUnit(cmd.to)
+	values, _, ok := valueList([]byte(from))
 	if !ok {
-		return fmt.Sprintf("Invalid unit %s", cmd.to)
+		slog.Info("Invalid command", "expr", from, "values", values)
+		return "Usage: !conv [amount][from-unit] to [to-unit] [on YYYY-MM-DD]"
+	}
+	targets, n, ok := targetList([]byte(to))
+	if !ok || strings.TrimSpace(to[n:]) != "" {
+		targets = []string{to}
 	}
 
-	slog.Debug("converting", "from", from, "to", toUnit)
+	return convertAll(scope, values, targets, on)
+}
 
-	to, err := from.Convert(toUnit)
+// convertAll converts each value on its own to each of the targets, so one bad value doesn't fail the others.
+// More than one conversion is listed as an aligned table
+func convertAll(scope Scope, values []any, to []string, on string) string {
+	for _, name := range to {
+		if _, isTarget := lookupTarget(name); !isTarget {
+			if _, ok := lookupUnitIn(scope, name); !ok {
+				return ErrorInvalidUnit{name}.Error()
+			}
+		}
+	}
+	date, err := parseAsOf(on)
 	if err != nil {
 		return err.Error()
 	}
 
-	return fmt.Sprintf("%s = %s", from, to)
-}
-
-func Convert(from, to string) string {
-	cmd, _, ok := fromExpr([]byte(from))
-	if !ok {
-		slog.Info("Invalid command: `%v` %v\n", from, cmd)
-		return "Usage: !conv [amount][from-unit] to [to-unit]"
+	var rows []conversionRow
+	var units []UnitType
+	// converted are the units of the conversions that succeeded, so failures aren't noted as using past rates
+	var converted []UnitType
+	var rates []exchangeRate
+	for _, v := range values {
+		fromValue, err := resolveValue(scope, v)
+		if err != nil {
+			rows = append(rows, conversionRow{describeValue(v), err.Error(), true})
+			continue
+		}
+		fromValue = asOf(fromValue, date)
+		units = append(units, fromValue.Unit())
+
+		for _, name := range to {
+			if target, isTarget := lookupTarget(name); isTarget {
+				reply, err := target(fromValue)
+				if err != nil {
+					rows = append(rows, conversionRow{fromValue.String(), err.Error(), true})
+					continue
+				}
+				rows = append(rows, conversionRow{result: reply})
+				continue
+			}
+			toUnit, _ := lookupUnitIn(scope, name)
+			units = append(units, toUnit)
+
+			slog.Debug("converting", "from", debug(fromValue), "to", debug(toUnit))
+
+			toValue, err := fromValue.Convert(toUnit)
+			if err != nil {
+				slog.Error("Cannot convert",
+					"fromValue", debug(fromValue), "toUnit", debug(toUnit), "err", err)
+				rows = append(rows, conversionRow{fromValue.String(), err.Error(), true})
+				continue
+			}
+			if rate, ok := rateUsed(fromValue, toUnit); ok {
+				rates = append(rates, rate)
+			}
+			converted = append(converted, fromValue.Unit(), toUnit)
+			rows = append(rows, conversionRow{from: fromValue.String(), result: toValue.String()})
+		}
 	}
 
-	var fromValue UnitVal
-	switch uv := cmd.(type) {
-	case unparsedUnitVal:
-		fromUnit, ok := LookupUnit(uv.unit)
-		if !ok {
-			return fmt.Sprintf("Invalid unit %s", uv.unit)
-		}
+	reply := formatRows(rows)
+	if len(rows) == 1 && rows[0].failed {
+		reply = rows[0].result
+	} else if len(rows) == 1 {
+		reply = rows[0].String()
+	}
+	return annotateRates(annotateAsOf(annotate(reply, units...), date, converted...), rates)
+}
 
-		fromValue = fromUnit.FromFloat(uv.val)
+// conversionRow is one line of a conversion reply
+type conversionRow struct {
+	from, result string
+	// failed rows have an error as their result, labelled with the value that failed
+	failed bool
+}
 
-	case UnitVal:
-		fromValue = uv
+func (row conversionRow) String() string {
+	switch {
+	case row.from == "":
+		return row.result
+	case row.failed:
+		return fmt.Sprintf("%s: %s", row.from, row.result)
+	default:
+		return fmt.Sprintf("%s = %s", row.from, row.result)
 	}
+}
 
-	toUnit, ok := LookupUnit(to)
-	if !ok {
-		return fmt.Sprintf("Invalid unit %s", to)
+// formatRows lines up the = of each row in a code block
+func formatRows(rows []conversionRow) string {
+	width := 0
+	for _, row := range rows {
+		if n := utf8.RuneCountInString(row.from); n > width {
+			width = n
+		}
 	}
 
-	slog.Debug("converting", "from", debug(fromValue), "to", debug(toUnit))
-
-	toValue, err := fromValue.Convert(toUnit)
-	if err != nil {
-		slog.Error("Cannot convert",
-			"fromValue", debug(fromValue), "toUnit", debug(toUnit), "err", err)
-		return err.Error()
+	var reply strings.Builder
+	reply.WriteString("```\n")
+	for _, row := range rows {
+		if row.from != "" {
+			reply.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(row.from)))
+		}
+		reply.WriteString(row.String())
+		reply.WriteString("\n")
 	}
-
-	return fmt.Sprintf("%s = %s", fromValue, toValue)
+	reply.WriteString("```")
+	return reply.String()
 }
 
 func debug(v any) string {
 	return fmt.Sprintf("%#v", v)
 }
 
-func Autocomplete(from, to string) []string {
-	cmd, _, ok := fromExpr([]byte(from))
+// Autocomplete suggests units to convert a value to that start with to, with the units of the value looked up in a scope
+func Autocomplete(scope Scope, from, to string) []string {
+	values, _, ok := valueList([]byte(from))
 	if !ok {
-		slog.Info("Invalid command: `%v` %v\n", from, cmd)
+		slog.Info("Invalid command", "expr", from, "values", values)
 		return nil
 	}
 
-	var fromValue UnitVal
-	switch uv := cmd.(type) {
-	case unparsedUnitVal:
-		fromUnit, ok := LookupUnit(uv.unit)
-		if !ok {
-			return nil
-		}
-
-		fromValue = fromUnit.FromFloat(uv.val)
-
-	case UnitVal:
-		fromValue = uv
+	fromValue, err := resolveValue(scope, values[0])
+	if err != nil {
+		return nil
 	}
 
 	options := []string{}
@@ -122,22 +191,185 @@ type unparsedUnitVal struct {
 	unit string
 }
 
-type command struct {
+type unparsedUncertainVal struct {
 	from any
-	to   string
+	tol  tolerance
+}
+
+// tolerance is the ± part of a value with uncertainty
+type tolerance struct {
+	delta    float64
+	unit     string
+	relative bool
+}
+
+// describeValue writes a parsed value the way it was given, for when its units can't be found
+func describeValue(v any) string {
+	switch v := v.(type) {
+	case unparsedUnitVal:
+		return fmt.Sprintf("%g %s", v.val, v.unit)
+	case unparsedUncertainVal:
+		tol := fmt.Sprintf("%g%s", v.tol.delta, v.tol.unit)
+		if v.tol.relative {
+			tol = fmt.Sprintf("%g%%", v.tol.delta*100)
+		}
+		return fmt.Sprintf("%s ± %s", describeValue(v.from), tol)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// resolveValue looks up the units of a parsed value
+func resolveValue(scope Scope, v any) (UnitVal, error) {
+	switch v := v.(type) {
+	case unparsedUnitVal:
+		u, ok := lookupUnitIn(scope, v.unit)
+		if !ok {
+			return nil, ErrorInvalidUnit{v.unit}
+		}
+		return withConstantUncertainty(u.FromFloat(v.val)), nil
+
+	case unparsedUncertainVal:
+		val, err := resolveValue(scope, v.from)
+		if err != nil {
+			return nil, err
+		}
+		return v.tol.apply(val)
+
+	case relativeDay:
+		return v.in(homeZone(scope)), nil
+
+	case UnitVal:
+		return v, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected value %v", v)
+	}
+}
+
+func (t tolerance) apply(val UnitVal) (UnitVal, error) {
+	switch {
+	case t.relative:
+		return NewRelativeUncertainVal(val, t.delta), nil
+	case t.unit == "":
+		return UncertainVal{val, t.delta}, nil
+	default:
+		u, ok := LookupUnit(t.unit)
+		if !ok {
+			return nil, ErrorInvalidUnit{t.unit}
+		}
+		delta, err := convertDelta(u, 0, t.delta, val.Unit())
+		if err != nil {
+			return nil, err
+		}
+		return UncertainVal{val, delta}, nil
+	}
+}
+
+// runner is a parsed command that produces a reply
+type runner interface {
+	run(scope Scope) string
+}
+
+// runnable turns a command parser into a parser for Process
+func runnable[T runner](parser p.Parser[T]) p.Parser[runner] {
+	return p.Map(parser, func(cmd T) runner { return cmd })
+}
+
+type command struct {
+	from []any
+	to   []string
+	// on is the date of the exchange rates to use, the latest if empty
+	on string
 }
 
 var (
-	unitToken     = p.Token(`[A-Za-z+/$€¥£]+`)
+	// unitToken only continues past a + into a letter, e.g. ft+in, so 3kg+-5% is kg with a tolerance
+	unitToken     = p.Token(`(?:const:\w+|S?Fr\.|[A-Za-z+/$€¥£](?:[A-Za-z0-9_/·()²³$€¥£]|\+[A-Za-z]|\^-?\d+|\*[A-Za-z(])*)`).Filter(notKeyword)
 	inches        = p.Parse2(p.Int, p.RuneIn(`"”`).Opt(), fst[int, rune])
 	feet          = p.Parse2(p.Int, p.RuneIn(`'’`), fst[int, rune])
 	feetInches    = p.Parse2(feet, inches.Or(0), mapFeetInches)
-	simpleUnitVal = p.Parse2(p.Float, unitToken, mapSimpleUnit)
-	currency      = p.Parse2(p.RuneIn(`$€¥£`), p.Float, mapCurrency)
-	fromExpr      = p.First(simpleUnitVal, feetInches, currency)
-	convertExpr   = p.Parse3(fromExpr, p.Atom(`to`), unitToken, func(v any, _ string, u string) command { return command{v, u} })
+	simpleUnitVal = p.Parse2(p.Float, sourceUnit, mapSimpleUnit)
+	// currencyPrefix is a currency symbol written before an amount, e.g. $5 or C$5
+	currencyPrefix = p.Token(`(?:[A-Z]{1,3}[$¥]|S?Fr\.|[$€¥£])`)
+	currency       = p.Parse2(currencyPrefix, p.Float, mapCurrency)
+	exactVal       = p.First(dateVal, relativeDate, isoDurationVal, simpleUnitVal, feetInches, currency, epochVal)
+
+	plusMinus     = p.Token(`(±|\+/-|\+-)`)
+	relTolerance  = p.Parse2(p.Float, p.Atom(`%`), mapRelTolerance)
+	bareTolerance = p.First(relTolerance, p.Map(p.Float, mapBareTolerance))
+	absTolerance  = p.Parse2(p.Float, unitToken.Or(""), mapAbsTolerance)
+	bareUncertain = p.Parse3(p.Float, plusMinus, bareTolerance, mapBareUncertain)
+	sharedUnitVal = p.Parse2(bareUncertain, unitToken, mapSharedUnit)
+	uncertainVal  = p.Parse3(exactVal, plusMinus, p.First(relTolerance, absTolerance), mapUncertain)
+
+	fromExpr  = p.First(sharedUnitVal, uncertainVal, exactVal)
+	valueList = p.SepBy(fromExpr, p.RuneIn(`,;`))
+	argTarget = p.Token(`[A-Za-z]+:\w+`)
+	// sourceUnit is the unit of a value, and unitName a unit or target to convert to, e.g. human:2
+	sourceUnit  = p.First(dateUnit, unitToken)
+	unitName    = p.First(dateUnit, argTarget, unitToken)
+	asOfExpr    = p.Parse2(p.Atom(`on`), dateToken, snd[string, string])
+	targetList  = p.SepBy(unitName, p.RuneIn(`,;`))
+	convertTo   = p.Parse2(targetList, asOfExpr.Or(""), func(to []string, on string) command { return command{to: to, on: on} })
+	convertExpr = p.Parse3(valueList, p.Atom(`to`), convertTo, func(vs []any, _ string, cmd command) command { cmd.from = vs; return cmd })
+
+	processExpr = p.First(
+		runnable(zoneExpr),
+		runnable(inflationExpr),
+		runnable(convertExpr),
+		runnable(epochExpr),
+		runnable(tableExpr),
+		runnable(explainExpr),
+		runnable(comparisonExpr),
+		runnable(compareListExpr),
+		runnable(calcExpr),
+		runnable(sumExpr),
+	)
 )
 
+// targets are words that can be converted to instead of a unit, producing a whole reply
+var targets = map[string]func(UnitVal) (string, error){
+	"relatable":   Relatable,
+	"all":         ConvertToAll,
+	"human":       Human,
+	"isoduration": ISODuration,
+}
+
+// argTargets are targets that take an argument after a colon, e.g. human:2
+var argTargets = map[string]func(UnitVal, string) (string, error){
+	"human": HumanDepth,
+}
+
+// lookupTarget finds a target by name, with its argument if it has one
+func lookupTarget(to string) (func(UnitVal) (string, error), bool) {
+	name, arg, hasArg := strings.Cut(strings.ToLower(to), ":")
+	if !hasArg {
+		target, ok := targets[name]
+		return target, ok
+	}
+	target, ok := argTargets[name]
+	if !ok {
+		return nil, false
+	}
+	return func(v UnitVal) (string, error) { return target(v, arg) }, true
+}
+
+// keywords are words in commands that can't be units
+var keywords = map[string]bool{
+	"to": true,
+}
+
+func notKeyword(s string) bool {
+	return !keywords[s]
+}
+
+func snd[A any, B any](a A, b B) B {
+	return b
+}
+
 func fst[A any, B any](a A, b B) A {
 	return a
 }
@@ -150,6 +382,32 @@ func mapFeetInches(feet int, inches int) any {
 	return FootInchVal{Feet: float64(feet), Inches: float64(inches)}
 }
 
-func mapCurrency(c rune, v float64) any {
-	return unparsedUnitVal{v, string(c)}
+func mapCurrency(symbol string, v float64) any {
+	return unparsedUnitVal{v, symbol}
+}
+
+func mapRelTolerance(v float64, _ string) tolerance {
+	return tolerance{delta: v / 100, relative: true}
+}
+
+func mapBareTolerance(v float64) tolerance {
+	return tolerance{delta: v}
+}
+
+func mapAbsTolerance(v float64, u string) tolerance {
+	return tolerance{delta: v, unit: u}
+}
+
+func mapBareUncertain(v float64, _ string, t tolerance) unparsedUncertainVal {
+	return unparsedUncertainVal{unparsedUnitVal{val: v}, t}
+}
+
+// mapSharedUnit applies a trailing unit to both the value and the uncertainty, e.g. 12.5 ± 0.2 mm
+func mapSharedUnit(v unparsedUncertainVal, u string) any {
+	v.from = unparsedUnitVal{v.from.(unparsedUnitVal).val, u}
+	return v
+}
+
+func mapUncertain(v any, _ string, t tolerance) any {
+	return unparsedUncertainVal{v, t}
 }
@@ -0,0 +1,41 @@
+package convert
+
+import "testing"
+
+// replyTest is a command and the reply it should get
+type replyTest struct {
+	expr string
+	want string
+}
+
+// checkReplies runs each command and checks its whole reply
+func checkReplies(t *testing.T, scope Scope, tests []replyTest) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := ProcessIn(scope, tt.expr); got != tt.want {
+			t.Errorf("ProcessIn(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestTolerances(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"12.5±0.2 mm to in", "12.5 ± 0.2 mm = 0.492 ± 0.008 in"},
+		{"12.5 mm ± 0.2 mm to in", "12.5 ± 0.2 mm = 0.492 ± 0.008 in"},
+		{"3 kg ± 5% to lb", "3.00 ± 0.15 kg = 6.6 ± 0.3 lbs"},
+		{"3kg+-5% to lb", "3.00 ± 0.15 kg = 6.6 ± 0.3 lbs"},
+		{"10 m +/- 1 to ft", "10.0 ± 1.0 m = 33 ± 3 ft"},
+		{"5 kg ± 1 s to lb", "Can't convert from s to kg"},
+	})
+}
+
+func TestLists(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"5kg, 12kg, 20kg to lb", "```\n 5 kg = 11.0231 lbs\n12 kg = 26.4555 lbs\n20 kg = 44.0925 lbs\n```"},
+		{"10 km; 21.1 km; 42.2 km to mi", "```\n  10 km = 6.21371 miles\n21.1 km = 13.1109 miles\n42.2 km = 26.2219 miles\n```"},
+		// Each value succeeds or fails on its own
+		{"10 km, 5 blah to mi", "```\n 10 km = 6.21371 miles\n5 blah: Invalid unit blah\n```"},
+		{"5 kg, 3 m to lb", "```\n5 kg = 11.0231 lbs\n 3 m: Can't convert from m to lbs\n```"},
+		{"5 blah to mi", "Invalid unit blah"},
+	})
+}
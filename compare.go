@@ -0,0 +1,132 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	p "unit-bot/parser"
+)
+
+// compareCommand orders values, optionally checking an operator between two of them
+type compareCommand struct {
+	values []any
+	op     string
+}
+
+var (
+	compareOp       = p.Token(`(>=|<=|==|!=|>|<|=)`)
+	comparisonExpr  = p.Parse3(fromExpr, compareOp, fromExpr, mapComparison)
+	compareListExpr = p.Parse2(p.Atom(`compare`), valueList, mapCompareList)
+)
+
+func mapComparison(a any, op string, b any) compareCommand {
+	return compareCommand{[]any{a, b}, op}
+}
+
+func mapCompareList(_ string, vs []any) compareCommand {
+	return compareCommand{values: vs}
+}
+
+// Compare orders a comma separated list of values
+func Compare(values string) string {
+	vs, _, ok := valueList([]byte(values))
+	if !ok {
+		return "Usage: /compare [amount][unit], [amount][unit], ..."
+	}
+	return compareCommand{values: vs}.run(Scope{})
+}
+
+type comparedVal struct {
+	val, common UnitVal
+}
+
+func (cmd compareCommand) run(scope Scope) string {
+	var vals []comparedVal
+	for _, v := range cmd.values {
+		val, err := resolveValue(scope, v)
+		if err != nil {
+			return err.Error()
+		}
+		vals = append(vals, comparedVal{val: val})
+	}
+
+	// Everything is compared in the unit of the first value
+	first := vals[0].val.Unit()
+	if first == UnitType(FootInch) {
+		first = Foot
+	}
+	for i, v := range vals {
+		if v.val.Unit().Dimension() != first.Dimension() {
+			return ErrorIncompatible{"compare", vals[0].val.Unit(), v.val.Unit()}.Error()
+		}
+		common, err := v.val.Convert(first)
+		if err != nil {
+			return err.Error()
+		}
+		vals[i].common = common
+	}
+
+	var reply strings.Builder
+	if cmd.op != "" {
+		c, err := vals[0].common.Cmp(vals[1].common)
+		if err != nil {
+			return err.Error()
+		}
+		if holds(c, cmd.op) {
+			fmt.Fprintf(&reply, "Yes, %s %s %s\n", vals[0].val, cmd.op, vals[1].val)
+		} else {
+			fmt.Fprintf(&reply, "No, %s is not %s %s\n", vals[0].val, cmd.op, vals[1].val)
+		}
+	}
+
+	sort.SliceStable(vals, func(i, j int) bool {
+		return magnitude(vals[i].common) < magnitude(vals[j].common)
+	})
+
+	for i, v := range vals {
+		if i > 0 {
+			if magnitude(v.common) == magnitude(vals[i-1].common) {
+				reply.WriteString(" = ")
+			} else {
+				reply.WriteString(" < ")
+			}
+		}
+		reply.WriteString(v.val.String())
+	}
+
+	for i, v := range vals {
+		reply.WriteString("\n")
+		reply.WriteString(v.val.String())
+		if v.common.String() != v.val.String() {
+			fmt.Fprintf(&reply, " = %s", v.common)
+		}
+		if i > 0 && magnitude(v.common) != magnitude(vals[i-1].common) {
+			diff, err := v.common.Sub(vals[i-1].common)
+			if err != nil {
+				return err.Error()
+			}
+			fmt.Fprintf(&reply, " (+%s)", diff)
+		}
+	}
+
+	return reply.String()
+}
+
+// holds checks a comparison operator against the result of Cmp
+func holds(c int, op string) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case "!=":
+		return c != 0
+	default:
+		return c == 0
+	}
+}
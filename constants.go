@@ -0,0 +1,129 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PhysicalConstant is a physical quantity with a fixed value
+type PhysicalConstant struct {
+	Symbol      string
+	Name        string
+	Value       float64
+	Uncertainty float64
+	Dims        Dims
+	Source      string
+	// Aliases are extra case sensitive symbols the constant can be used as a unit with
+	Aliases []string
+}
+
+// constantPrefix is written before the symbol of a constant to use it as a unit, e.g. 1 const:G.
+// It's needed for the symbols in prefixedConstants, and allowed for the rest
+const constantPrefix = "const:"
+
+// prefixedConstants are the symbols that clash with units, so they're only constants with constantPrefix,
+// e.g. G is a gram and h is the usual symbol for an hour
+var prefixedConstants = map[string]bool{"c": true, "G": true, "h": true}
+
+// Constants usable as units, e.g. 9.8 const:g0 to m/s^2
+var Constants = []*PhysicalConstant{
+	{"c", "speed of light in vacuum", 299792458, 0, Dims{1, 0, -1, 0, 0, 0, 0}, "SI definition of the metre (exact)", []string{"c0"}},
+	{"g0", "standard acceleration of gravity", 9.80665, 0, Dims{1, 0, -2, 0, 0, 0, 0}, "3rd CGPM, 1901 (exact)", []string{"g_n"}},
+	{"G", "Newtonian constant of gravitation", 6.67430e-11, 0.00015e-11, Dims{3, -1, -2, 0, 0, 0, 0}, "CODATA 2018", nil},
+	{"h", "Planck constant", 6.62607015e-34, 0, Dims{2, 1, -1, 0, 0, 0, 0}, "SI definition of the kilogram (exact)", nil},
+	{"N_A", "Avogadro constant", 6.02214076e23, 0, Dims{0, 0, 0, 0, 0, -1, 0}, "SI definition of the mole (exact)", []string{"NA"}},
+	{"k_B", "Boltzmann constant", 1.380649e-23, 0, Dims{2, 1, -2, 0, -1, 0, 0}, "SI definition of the kelvin (exact)", []string{"kB"}},
+	{"R", "molar gas constant", 8.314462618, 0, Dims{2, 1, -2, 0, -1, -1, 0}, "N_A × k_B, CODATA 2018 (exact)", nil},
+}
+
+// constantUnits are the constants as units by their case sensitive symbols,
+// and unitConstants the constants of those units
+var constantUnits, unitConstants = constantUnitMaps()
+
+func constantUnitMaps() (map[string]*DerivedUnit, map[*DerivedUnit]*PhysicalConstant) {
+	units := map[string]*DerivedUnit{}
+	constants := map[*DerivedUnit]*PhysicalConstant{}
+	for _, c := range Constants {
+		u := &DerivedUnit{constantName(c.Symbol), siDef{Factor: c.Value, Dims: c.Dims}}
+		for _, symbol := range append([]string{c.Symbol}, c.Aliases...) {
+			units[symbol] = u
+		}
+		constants[u] = c
+	}
+	return units, constants
+}
+
+// constantName is how a constant is written in conversions, e.g. g0 or const:G
+func constantName(symbol string) string {
+	if prefixedConstants[symbol] {
+		return constantPrefix + symbol
+	}
+	return symbol
+}
+
+// withConstantUncertainty gives a value in a constant its share of the constant's standard uncertainty,
+// so it's carried through calculations
+func withConstantUncertainty(v UnitVal) UnitVal {
+	u, ok := v.Unit().(*DerivedUnit)
+	if !ok {
+		return v
+	}
+	if c, ok := unitConstants[u]; ok && c.Uncertainty != 0 {
+		return NewRelativeUncertainVal(v, c.Uncertainty/c.Value)
+	}
+	return v
+}
+
+// LookupConstant finds a constant by its symbol, or by its name ignoring case
+func LookupConstant(s string) (*PhysicalConstant, bool) {
+	for _, c := range Constants {
+		if c.Symbol == s {
+			return c, true
+		}
+	}
+	for _, c := range Constants {
+		if strings.EqualFold(c.Name, s) || containsFold(c.Aliases, s) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+func containsFold(ss []string, s string) bool {
+	for _, v := range ss {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *PhysicalConstant) String() string {
+	value := fmt.Sprintf("%.10g", c.Value)
+	if c.Uncertainty != 0 {
+		value = formatUncertain(c.Value, c.Uncertainty)
+	}
+	return fmt.Sprintf("%s (%s) = %s %s\nSource: %s\nUse it in conversions as %s", c.Symbol, c.Name, value, c.Dims, c.Source, constantName(c.Symbol))
+}
+
+// Constant looks up a physical constant for the !const command
+func Constant(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		var symbols []string
+		for _, c := range Constants {
+			symbols = append(symbols, c.Symbol)
+		}
+		sort.Strings(symbols)
+		return "Usage: !const [symbol]\nConstants: " + strings.Join(symbols, ", ") +
+			"\nUse them in conversions by their symbols, e.g. 9.8 g0 to m/s^2, or with " + constantPrefix +
+			" for c, G and h, which are also units, e.g. 1 const:G"
+	}
+
+	c, ok := LookupConstant(name)
+	if !ok {
+		return fmt.Sprintf("Unknown constant %s", name)
+	}
+	return c.String()
+}
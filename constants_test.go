@@ -0,0 +1,32 @@
+package convert
+
+import "testing"
+
+func TestConstants(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"9.8 g0 to m/s^2", "9.8 g0 = 96.1052 m/s^2"},
+		{"1 N_A * 18 g/mol", "1 N_A × 18 g/mol = 1.08399e+22 kg/mol^2"},
+		{"2 k_B to J/K", "2 k_B = 2.7613e-23 J/K"},
+		{"1 const:g0 to m/s^2", "1 g0 = 9.80665 m/s^2"},
+		{"1 c0 to m/s", "1 const:c = 2.99792e+08 m/s"},
+		// Symbols that clash with units are the units without the prefix
+		{"5 G to kg", "5 g = 0.005 kg"},
+		{"5 c to F", "5 °C = 41 °F"},
+		{"1 const:G to N", "Can't convert from const:G to N"},
+		{"1 g0 to const:c", "Can't convert from g0 to const:c"},
+	})
+}
+
+func TestConstantUncertainty(t *testing.T) {
+	u, ok := LookupUnit("const:G")
+	if !ok {
+		t.Fatal("const:G not found")
+	}
+	v := withConstantUncertainty(u.FromFloat(2))
+	if got, want := uncertainty(v), 2*0.00015e-11/6.67430e-11; !approx(got, want) {
+		t.Errorf("uncertainty(2 G) = %v, want %v", got, want)
+	}
+	if u, ok := LookupUnit("n_a"); ok {
+		t.Errorf("LookupUnit(n_a) = %s, want symbols to be case sensitive", u)
+	}
+}
@@ -0,0 +1,168 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CryptoAsset is a cryptocurrency that can be converted like a currency
+type CryptoAsset struct {
+	// Code is the ticker it's used as a unit by, e.g. BTC
+	Code string
+	Name string
+	// ID is what the rate provider calls it, e.g. bitcoin on CoinGecko
+	ID string
+	// Decimals is the smallest fraction it can be divided into, e.g. 8 for satoshis
+	Decimals      int
+	Denominations []CryptoDenomination
+}
+
+// CryptoDenomination is an exact fraction of a crypto asset with its own name, e.g. 1 sat = 1e-8 BTC
+type CryptoDenomination struct {
+	Name string
+	// Exp is the power of ten of the asset one of it is worth, e.g. -8 for sat
+	Exp     int
+	Aliases []string
+}
+
+var (
+	cryptoLock sync.RWMutex
+	// cryptoAssets are the crypto assets by code, added to by AddCryptoAsset
+	cryptoAssets = map[string]CryptoAsset{
+		"BTC": {"BTC", "Bitcoin", "bitcoin", 8, []CryptoDenomination{
+			{"mBTC", -3, []string{"millibitcoin", "millibitcoins"}},
+			{"µBTC", -6, []string{"uBTC", "bit", "bits"}},
+			{"sat", -8, []string{"sats", "satoshi", "satoshis"}},
+		}},
+		"ETH": {"ETH", "Ether", "ethereum", 18, []CryptoDenomination{
+			{"gwei", -9, []string{"shannon"}},
+			{"wei", -18, nil},
+		}},
+	}
+)
+
+// AddCryptoAsset adds or replaces a crypto asset, which is converted once its rate provider's currencies are loaded
+func AddCryptoAsset(asset CryptoAsset) {
+	cryptoLock.Lock()
+	defer cryptoLock.Unlock()
+	asset.Code = strings.ToUpper(asset.Code)
+	cryptoAssets[asset.Code] = asset
+}
+
+func lookupCryptoAsset(code string) (CryptoAsset, bool) {
+	cryptoLock.RLock()
+	defer cryptoLock.RUnlock()
+	asset, ok := cryptoAssets[code]
+	return asset, ok
+}
+
+// cryptoAssetList is the crypto assets ordered by code
+func cryptoAssetList() []CryptoAsset {
+	cryptoLock.RLock()
+	defer cryptoLock.RUnlock()
+	assets := make([]CryptoAsset, 0, len(cryptoAssets))
+	for _, asset := range cryptoAssets {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Code < assets[j].Code })
+	return assets
+}
+
+// coinGeckoURL is the API of coingecko.com
+const coinGeckoURL = "https://api.coingecko.com/api/v3"
+
+// CoinGeckoProvider gets the prices of the crypto assets from the CoinGecko API
+type CoinGeckoProvider struct {
+	// URL is the base of the API, coinGeckoURL if empty
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func (c CoinGeckoProvider) Name() string {
+	u, err := url.Parse(c.baseURL())
+	if err != nil {
+		return c.baseURL()
+	}
+	return u.Host
+}
+
+func (c CoinGeckoProvider) baseURL() string {
+	if c.URL == "" {
+		return coinGeckoURL
+	}
+	return c.URL
+}
+
+// Currencies are the crypto assets, since they're all CoinGecko has prices for that are useful as units
+func (c CoinGeckoProvider) Currencies() (map[string]string, error) {
+	currencies := map[string]string{}
+	for _, asset := range cryptoAssetList() {
+		currencies[asset.Code] = asset.Name
+	}
+	return currencies, nil
+}
+
+// Rates gets the prices of every crypto asset in one call, in USD and the other currencies asked for,
+// and rebases them so crypto can be converted to crypto as well as to currencies
+func (c CoinGeckoProvider) Rates(base string, symbols []string) (Rates, error) {
+	crypto := false
+	for _, code := range append([]string{base}, symbols...) {
+		_, ok := lookupCryptoAsset(code)
+		crypto = crypto || ok
+	}
+	if !crypto {
+		// Rates between currencies are left to the providers that publish them
+		return Rates{}, ErrorUnknownCurrency{base}
+	}
+
+	assets := cryptoAssetList()
+	var ids []string
+	for _, asset := range assets {
+		ids = append(ids, asset.ID)
+	}
+	vs := []string{"usd"}
+	for _, code := range append([]string{base}, symbols...) {
+		if _, ok := lookupCryptoAsset(code); !ok && code != "USD" {
+			vs = append(vs, strings.ToLower(code))
+		}
+	}
+
+	u, _ := url.Parse(c.baseURL() + "/simple/price")
+	q := url.Values{"ids": {strings.Join(ids, ",")}, "vs_currencies": {strings.Join(vs, ",")}}
+	if c.APIKey != "" {
+		q.Set("x_cg_demo_api_key", c.APIKey)
+	}
+	u.RawQuery = q.Encode()
+	body, err := getBody(c.Client, u.String())
+	if err != nil {
+		return Rates{}, err
+	}
+	var prices map[string]map[string]float64
+	if err := json.Unmarshal(body, &prices); err != nil {
+		return Rates{}, fmt.Errorf("unable to decode prices response: %w\nBody: %v", err, string(body))
+	}
+
+	// Everything is worked out in USD: each asset is 1/price, and other currencies are priced through any asset
+	rates := Rates{Base: "USD", Rates: map[string]float64{}, Source: c.Name(), Time: time.Now().UTC()}
+	for _, asset := range assets {
+		price := prices[asset.ID]
+		usd := price["usd"]
+		if usd == 0 {
+			continue
+		}
+		rates.Rates[asset.Code] = 1 / usd
+		for _, currency := range vs[1:] {
+			if p := price[currency]; p != 0 {
+				rates.Rates[strings.ToUpper(currency)] = p / usd
+			}
+		}
+	}
+	return rates.rebase(base, symbols)
+}
@@ -0,0 +1,16 @@
+package convert
+
+import "testing"
+
+func TestCryptoDenominations(t *testing.T) {
+	withCurrencies(t, map[string]string{"ETH": "Ether", "BTC": "Bitcoin"})
+	checkReplies(t, Scope{}, []replyTest{
+		{"1 ETH to wei", "1 ETH = 1000000000000000000 wei"},
+		{"1 wei to ETH", "1 wei = 0.000000000000000001 ETH"},
+		{"3 gwei to wei", "3 gwei = 3000000000 wei"},
+		{"0.1 BTC to sat", "0.1 BTC = 10000000 sat"},
+		{"5 sat to BTC", "5 sat = 0.00000005 BTC"},
+		{"123456789 sat to BTC", "123456789 sat = 1.23456789 BTC"},
+		{"1 mBTC to sat", "1 mBTC = 100000 sat"},
+	})
+}
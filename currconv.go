@@ -0,0 +1,100 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// currConvURL is the API of free.currconv.com
+const currConvURL = "https://free.currconv.com/api/v7"
+
+// CurrConvProvider gets rates from the free.currconv.com API, which needs an API key
+type CurrConvProvider struct {
+	// URL is the base of the API, currConvURL if empty
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+type supportedCurrencies struct {
+	Results map[string]struct {
+		ID           string
+		CurrencyName string
+	}
+}
+
+func (c CurrConvProvider) Name() string {
+	u, err := url.Parse(c.baseURL())
+	if err != nil {
+		return c.baseURL()
+	}
+	return u.Host
+}
+
+func (c CurrConvProvider) baseURL() string {
+	if c.URL == "" {
+		return currConvURL
+	}
+	return c.URL
+}
+
+// endpoint is the URL of an API endpoint, with the API key and query
+func (c CurrConvProvider) endpoint(path string, query url.Values) string {
+	u, _ := url.Parse(c.baseURL() + "/" + path)
+	query.Set("apiKey", c.APIKey)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (c CurrConvProvider) Currencies() (map[string]string, error) {
+	if c.APIKey == "" {
+		return nil, ErrorCurrencyService
+	}
+	body, err := getBody(c.Client, c.endpoint("currencies", url.Values{}))
+	if err != nil {
+		return nil, err
+	}
+
+	var response supportedCurrencies
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unable to decode currencies response: %w\nBody: %v", err, string(body))
+	}
+	currencies := make(map[string]string, len(response.Results))
+	for _, curr := range response.Results {
+		currencies[curr.ID] = curr.CurrencyName
+	}
+	return currencies, nil
+}
+
+// Rates gets a rate for each symbol, since the free plan only converts a couple of pairs at a time.
+// It can't get every rate at once
+func (c CurrConvProvider) Rates(base string, symbols []string) (Rates, error) {
+	if c.APIKey == "" {
+		return Rates{}, ErrorCurrencyService
+	}
+	if symbols == nil {
+		return Rates{}, ErrorNoRateTable
+	}
+	rates := Rates{Base: base, Rates: make(map[string]float64, len(symbols)), Source: c.Name(), Time: time.Now().UTC()}
+	for _, symbol := range symbols {
+		op := base + "_" + symbol
+		body, err := getBody(c.Client, c.endpoint("convert", url.Values{"compact": {"ultra"}, "q": {op}}))
+		if err != nil {
+			return Rates{}, err
+		}
+
+		var response map[string]float64
+		if err := json.Unmarshal(body, &response); err != nil {
+			return Rates{}, fmt.Errorf("unable to decode rates response: %w\nBody: %v", err, string(body))
+		}
+		rate, ok := response[op]
+		if !ok {
+			return Rates{}, fmt.Errorf("unexpected response: %v", response)
+		}
+		rates.Rates[symbol] = rate
+	}
+	return rates, nil
+}
@@ -1,112 +1,165 @@
 package convert
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
-	"net/url"
-	"sync"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
 )
 
-const (
-	endpoint           = "https://free.currconv.com/api/v7"
-	endpointCurrencies = endpoint + "/currencies"
-	endpointConvert    = endpoint + "/convert"
-)
-
 var (
 	// ErrorCurrencyService occurs when there is an error while calling the currency service
 	ErrorCurrencyService = errors.New("currency conversion not available right now")
 
-	currencyApiKey string
-	currencyOnce   sync.Once
-	currencyCache  *cache.Cache = cache.New(24*time.Hour, 1*time.Hour)
+	rateProvider  RateProvider
+	currencyCache *cache.Cache = cache.New(24*time.Hour, 1*time.Hour)
+	// historyProvider is where the rates of past dates come from
+	historyProvider HistoricalRateProvider
+	// rateBase is the currency cross rates are worked out through
+	rateBase = "EUR"
 
+	// extraAliases are names currencies can be used by besides their codes.
+	// Symbols several currencies use, like $, are in ambiguousSymbols instead
 	extraAliases = map[string][]string{
-		"USD": {"$", "US$", "dollar", "dollars"},
+		"USD": {"US$"},
 		"EUR": {"€", "euro", "euros"},
-		"JPY": {"¥", "yen"},
-		"GBP": {"£"},
-		"CAD": {"CA$"},
-		"AUS": {"AUS$"},
+		"JPY": {"yen"},
+		"CNY": {"CN¥", "yuan", "RMB"},
+		"CAD": {"CA$", "C$"},
+		"AUD": {"A$", "AU$"},
+		"NZD": {"NZ$"},
+		"HKD": {"HK$"},
+		"SGD": {"S$"},
+		"MXN": {"MX$"},
+		"BRL": {"R$"},
+		"CHF": {"Fr.", "SFr."},
 	}
 )
 
-func SetCurrencyApiKey(apiKey string) {
-	currencyApiKey = apiKey
+// SetRateProviders sets where exchange rates come from.
+// Each provider is tried in turn until one of them answers
+func SetRateProviders(providers ...RateProvider) {
+	if len(providers) == 1 {
+		rateProvider = providers[0]
+	} else if len(providers) > 1 {
+		rateProvider = ChainProvider(providers)
+	}
 }
 
-type supportedCurrencies struct {
-	Results map[string]struct {
-		ID           string
-		CurrencyName string
+// SetHistoricalRateProviders sets where the exchange rates of past dates come from.
+// Each provider is tried in turn until one of them answers
+func SetHistoricalRateProviders(providers ...HistoricalRateProvider) {
+	if len(providers) == 1 {
+		historyProvider = providers[0]
+	} else if len(providers) > 1 {
+		var chain ChainProvider
+		for _, provider := range providers {
+			chain = append(chain, provider)
+		}
+		historyProvider = chain
 	}
 }
 
-func loadCurrencies() {
-	if currencyApiKey == "" {
-		slog.Info("Currency API key was not set. Currency conversion is not available")
-		return
+// SetRateBase sets the currency whose rates every cross rate is worked out from
+func SetRateBase(code string) {
+	rateBase = strings.ToUpper(code)
+}
+
+// SetCurrencyApiKey gets exchange rates from free.currconv.com with an API key
+func SetCurrencyApiKey(apiKey string) {
+	if apiKey != "" {
+		SetRateProviders(CurrConvProvider{APIKey: apiKey})
 	}
-	slog.Info("Loading currencies..")
+}
 
-	currencies, err := retrieveSupportedCurrencies()
-	if err != nil {
-		slog.Error("Error loading currencies:", err)
-		return
+// loadCurrencies registers the currencies of the rate providers, or the stored currencies if they can't be loaded
+func loadCurrencies() error {
+	if rateProvider == nil {
+		slog.Info("No exchange rate providers were set. Currency conversion is not available")
+		return ErrorCurrencyService
 	}
+	slog.Info("Loading currencies..", "provider", rateProvider.Name())
 
-	slog.Info("Supported currencies:")
-	unitLock.Lock()
-	defer unitLock.Unlock()
-	for _, curr := range currencies.Results {
-		slog.Info(curr.ID, curr.CurrencyName)
-		unit := &CurrencyUnit{curr.ID}
-		supportedUnits[unit] = append(supportedUnits[unit], curr.ID)
-		if aliases, ok := extraAliases[unit.id]; ok {
-			supportedUnits[unit] = append(supportedUnits[unit], aliases...)
+	currencies, err := rateProvider.Currencies()
+	if err != nil {
+		slog.Error("Error loading currencies", "err", err)
+		if stored := storedCurrencies(); len(stored) > 0 {
+			slog.Warn("Using stored currencies", "count", len(stored))
+			registerCurrencies(stored)
 		}
+		return err
 	}
-	refreshUnitMaps()
+	storeCurrencies(currencies)
+	registerCurrencies(currencies)
 
 	slog.Info("Currencies loaded")
+	return nil
 }
 
-func retrieveSupportedCurrencies() (*supportedCurrencies, error) {
-	currencyURL, _ := url.Parse(endpointCurrencies)
-	q := currencyURL.Query()
-	q.Set("apiKey", currencyApiKey)
-	currencyURL.RawQuery = q.Encode()
-
-	resp, err := http.Get(currencyURL.String())
-	if err != nil {
-		return nil, fmt.Errorf("Error calling currency service: %w", err)
-	}
-	defer resp.Body.Close()
+// currencyUnits are the registered currencies by code, so they are only registered once
+var currencyUnits = map[string]*CurrencyUnit{}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read response body: %w", err)
+// registerCurrencies adds currencies that aren't registered yet to the supported units
+func registerCurrencies(currencies map[string]string) {
+	unitLock.Lock()
+	defer unitLock.Unlock()
+	added := false
+	for code, name := range currencies {
+		if _, ok := currencyUnits[code]; ok {
+			continue
+		}
+		slog.Debug(code, "name", name)
+		unit := &CurrencyUnit{id: code, decimals: minorUnits(code)}
+		currencyUnits[code] = unit
+		supportedUnits[unit] = append(supportedUnits[unit], code)
+		if aliases, ok := extraAliases[unit.id]; ok {
+			supportedUnits[unit] = append(supportedUnits[unit], aliases...)
+		}
+		if asset, ok := lookupCryptoAsset(code); ok {
+			unit.decimals, unit.crypto = asset.Decimals, true
+			for _, d := range asset.Denominations {
+				denomination := &CurrencyUnit{id: d.Name, base: unit, exp: d.Exp, decimals: asset.Decimals + d.Exp, crypto: true}
+				currencyUnits[d.Name] = denomination
+				supportedUnits[denomination] = append([]string{d.Name}, d.Aliases...)
+			}
+		}
+		added = true
 	}
-
-	var response supportedCurrencies
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, fmt.Errorf("unable to decode currencies response: %w\nBody: %v", err, string(body))
+	if added {
+		refreshUnitMaps()
 	}
-
-	return &response, nil
 }
 
 // CurrencyUnit is a unit of currency
 type CurrencyUnit struct {
 	id string
+	// base is the currency it's a denomination of, e.g. BTC for sat, and exp the power of ten of the base
+	// one of it is worth, e.g. -8 for sat, which is 1e-8 BTC. base is nil for currencies
+	base *CurrencyUnit
+	exp  int
+	// decimals is the smallest fraction it can be divided into, its ISO 4217 minor units for currencies
+	decimals int
+	crypto   bool
+}
+
+// root is the currency it's a denomination of, or itself, with the power of ten of that one of it is worth
+func (cu *CurrencyUnit) root() (*CurrencyUnit, int) {
+	if cu.base == nil {
+		return cu, 0
+	}
+	return cu.base, cu.exp
+}
+
+// shiftPow10 multiplies a number by 10^exp, dividing by 10^-exp for negative powers,
+// since those aren't exact as floats but positive powers up to 1e22 are
+func shiftPow10(v float64, exp int) float64 {
+	if exp < 0 {
+		return v / math.Pow10(-exp)
+	}
+	return v * math.Pow10(exp)
 }
 
 // Name implements UnitType for Currency
@@ -116,7 +169,7 @@ func (cu *CurrencyUnit) String() string {
 
 // FromFloat implements SimpleUnit
 func (cu *CurrencyUnit) FromFloat(f float64) UnitVal {
-	return CurrencyVal{f, cu}
+	return CurrencyVal{V: f, U: cu}
 }
 
 func (cu *CurrencyUnit) Dimension() UnitDimension {
@@ -127,76 +180,248 @@ func (cu *CurrencyUnit) Dimension() UnitDimension {
 type CurrencyVal struct {
 	V float64
 	U *CurrencyUnit
+	// On is the date of the exchange rates it's converted at, the latest rates if zero
+	On time.Time
 }
 
+// String shows currencies in their minor units, and crypto to as many places as it's divided into,
+// up to about 10 significant digits without trailing zeros
 func (cv CurrencyVal) String() string {
-	return fmt.Sprintf("%.2f %s", cv.V, cv.U)
+	if !cv.U.crypto {
+		return formatMoney(cv.V, cv.U.decimals, false, cv.U.id)
+	}
+	places := cv.U.decimals
+	if cv.V != 0 {
+		if significant := 9 - int(math.Floor(math.Log10(math.Abs(cv.V)))); significant < places {
+			places = significant
+		}
+	}
+	if places < 0 {
+		places = 0
+	}
+	return formatMoney(cv.V, places, true, cv.U.id)
 }
 
 // Convert implements UnitVal conversion
 func (cv CurrencyVal) Convert(to UnitType) (UnitVal, error) {
 	if to, ok := to.(*CurrencyUnit); ok {
-		rate, err := getRate(cv.U, to)
+		if to == cv.U {
+			return cv, nil
+		}
+		rate, exp, err := currencyRate(cv.U, to, cv.On)
 		if err != nil {
-			if err != ErrorCurrencyService {
-				slog.Error("Error calling currency service,", err)
+			if errors.Is(err, ErrorNoHistoricalRates) {
+				slog.Warn("No historical rates", "on", cv.On, "err", err)
+				return nil, err
+			}
+			if !errors.Is(err, ErrorCurrencyService) {
+				slog.Error("Error calling currency service", "err", err)
 			}
 			return nil, ErrorCurrencyService
 		}
-		return CurrencyVal{cv.V * rate, to}, nil
+		return CurrencyVal{shiftPow10(cv.V*rate.Rate, exp), to, cv.On}, nil
 	}
 	return nil, ErrorConversion{cv.U, to}
 }
 
+func (cv CurrencyVal) Add(other UnitVal) (UnitVal, error) {
+	return combine("add", cv, other, 1)
+}
+
+func (cv CurrencyVal) Sub(other UnitVal) (UnitVal, error) {
+	return combine("subtract", cv, other, -1)
+}
+
+func (cv CurrencyVal) Mul(k float64) (UnitVal, error) {
+	return CurrencyVal{cv.V * k, cv.U, cv.On}, nil
+}
+
+func (cv CurrencyVal) Div(k float64) (UnitVal, error) {
+	if k == 0 {
+		return nil, ErrorDivideByZero
+	}
+	return CurrencyVal{cv.V / k, cv.U, cv.On}, nil
+}
+
+func (cv CurrencyVal) Neg() (UnitVal, error) {
+	return CurrencyVal{-cv.V, cv.U, cv.On}, nil
+}
+
+func (cv CurrencyVal) Abs() (UnitVal, error) {
+	return CurrencyVal{math.Abs(cv.V), cv.U, cv.On}, nil
+}
+
+func (cv CurrencyVal) Cmp(other UnitVal) (int, error) {
+	return compare(cv, other)
+}
+
+func (cv CurrencyVal) float() float64 {
+	return cv.V
+}
+
 func (cv CurrencyVal) Unit() UnitType {
 	return cv.U
 }
 
-func getRate(from, to *CurrencyUnit) (float64, error) {
+// exchangeRate is the rate between two currencies, with where and when it came from,
+// and the currency it was worked out through if it's a cross rate
+type exchangeRate struct {
+	Rate   float64
+	Source string
+	Time   time.Time
+	Base   string `json:",omitempty"`
+}
+
+// currencyRate is the rate between two currencies, or the currencies two denominations are of, on a date.
+// The power of ten between the denominations is returned apart from the rate, so it can be applied exactly
+func currencyRate(from, to *CurrencyUnit, on time.Time) (exchangeRate, int, error) {
+	fromRoot, fromExp := from.root()
+	toRoot, toExp := to.root()
+	if fromRoot == toRoot {
+		return exchangeRate{Rate: 1, Source: "exact", Time: on}, fromExp - toExp, nil
+	}
+	rate, err := getRateOn(fromRoot, toRoot, on)
+	if err != nil {
+		return exchangeRate{}, 0, err
+	}
+	return rate, fromExp - toExp, nil
+}
+
+// rateUsed finds the latest rate a value was converted to a unit at, if it was money converted to another currency
+func rateUsed(from UnitVal, to UnitType) (exchangeRate, bool) {
+	if uv, ok := from.(UncertainVal); ok {
+		from = uv.Val
+	}
+	cv, ok := from.(CurrencyVal)
+	if !ok || !cv.On.IsZero() {
+		return exchangeRate{}, false
+	}
+	toCurrency, ok := to.(*CurrencyUnit)
+	if !ok {
+		return exchangeRate{}, false
+	}
+	r, _, err := currencyRate(cv.U, toCurrency, cv.On)
+	return r, err == nil && r.Source != "exact"
+}
+
+// annotateRates notes where the exchange rates in a reply came from, through which base and when
+func annotateRates(reply string, rates []exchangeRate) string {
+	seen := map[string]bool{}
+	for _, r := range rates {
+		note := "Rates from " + r.Source
+		if r.Base != "" {
+			note += " through " + r.Base
+		}
+		note += " at " + r.Time.UTC().Format("2006-01-02 15:04 UTC")
+		if !seen[note] {
+			seen[note] = true
+			reply += "\n-# " + note
+		}
+	}
+	return reply
+}
+
+func getRate(from, to *CurrencyUnit) (exchangeRate, error) {
 	op := from.id + "_" + to.id
 	rate, ok := currencyCache.Get(op)
 	if ok {
-		slog.Debug("Cache hit:", op)
-		return rate.(float64), nil
+		slog.Debug("Cache hit", "op", op)
+		return rate.(exchangeRate), nil
+	}
+
+	// Cross rates come from the table of the base currency, so they only cost one call between them
+	if table, err := rateTable(); err == nil {
+		if r, ok := table.cross(from.id, to.id); ok {
+			if stored, ok := storedRate(op); !ok || !stored.Time.Equal(r.Time) {
+				storeRate(op, r)
+			}
+			return r, nil
+		}
 	} else {
-		slog.Debug("Cache miss:", op)
-		r, err := getRateNoCache(op)
-		if err != nil {
-			return 0, err
+		slog.Debug("No rate table", "base", rateBase, "err", err)
+	}
+
+	slog.Debug("Cache miss", "op", op)
+	var r exchangeRate
+	var err error
+	// A pair that just failed isn't asked for again until the backoff is over
+	if failed, ok := currencyCache.Get(failedKey(op)); ok {
+		err = failed.(error)
+	} else if r, err = fetchRate(from.id, to.id); err != nil {
+		currencyCache.Set(failedKey(op), err, rateFailureBackoff)
+	}
+	if err != nil {
+		// A stale rate is better than none while the providers are down
+		if stored, ok := storedRate(op); ok {
+			slog.Warn("Using stored rate", "op", op, "time", stored.Time, "err", err)
+			return stored, nil
 		}
-		currencyCache.Set(op, r, cache.DefaultExpiration)
-		return r, nil
+		return exchangeRate{}, err
 	}
+	currencyCache.Set(op, r, cache.DefaultExpiration)
+	storeRate(op, r)
+	return r, nil
 }
 
-func getRateNoCache(op string) (float64, error) {
-	if len(currencyApiKey) == 0 {
-		return 0, ErrorCurrencyService
-	}
+// rateTableKey is the cache key of the rate table from a base currency
+func rateTableKey(base string) string {
+	return "table:" + base
+}
 
-	convertURL, _ := url.Parse(endpointConvert)
-	q := convertURL.Query()
-	q.Set("apiKey", currencyApiKey)
-	q.Set("compact", "ultra")
-	q.Set("q", op)
-	convertURL.RawQuery = q.Encode()
+// failedKey is the cache key of the last error fetching something, so it isn't fetched again for a while
+func failedKey(key string) string {
+	return "failed:" + key
+}
+
+// rateFailureBackoff is how long a failed fetch isn't tried again,
+// so a provider that's down doesn't hold up every conversion until it times out
+const rateFailureBackoff = time.Minute
 
-	resp, err := http.Get(convertURL.String())
+// rateTable gets the rates from the base currency to every currency the rate providers have,
+// fetched in one call and cached
+func rateTable() (Rates, error) {
+	key := rateTableKey(rateBase)
+	if table, ok := currencyCache.Get(key); ok {
+		return table.(Rates), nil
+	}
+	if err, ok := currencyCache.Get(failedKey(key)); ok {
+		return Rates{}, err.(error)
+	}
+	table, err := fetchRateTable()
 	if err != nil {
-		return 0, err
+		currencyCache.Set(failedKey(key), err, rateFailureBackoff)
 	}
+	return table, err
+}
 
-	var response map[string]float64
-	decoder := json.NewDecoder(resp.Body)
-	err = decoder.Decode(&response)
+func fetchRateTable() (Rates, error) {
+	if rateProvider == nil {
+		return Rates{}, ErrorCurrencyService
+	}
+	table, err := rateProvider.Rates(rateBase, nil)
 	if err != nil {
-		return 0, err
+		return Rates{}, err
 	}
+	if len(table.Rates) == 0 {
+		return Rates{}, ErrorNoRates
+	}
+	slog.Info("Fetched rate table", "base", table.Base, "source", table.Source, "currencies", len(table.Rates))
+	currencyCache.Set(rateTableKey(rateBase), table, cache.DefaultExpiration)
+	return table, nil
+}
 
-	conversion, ok := response[op]
+// fetchRate gets a rate from the rate providers
+func fetchRate(from, to string) (exchangeRate, error) {
+	if rateProvider == nil {
+		return exchangeRate{}, ErrorCurrencyService
+	}
+	rates, err := rateProvider.Rates(from, []string{to})
+	if err != nil {
+		return exchangeRate{}, err
+	}
+	rate, ok := rates.rate(to)
 	if !ok {
-		return 0, fmt.Errorf("unexpected response: %v", response)
+		return exchangeRate{}, ErrorUnknownCurrency{to}
 	}
-
-	return conversion, nil
+	return exchangeRate{Rate: rate, Source: rates.Source, Time: rates.Time}, nil
 }
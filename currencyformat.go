@@ -0,0 +1,156 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CurrencyDisplay is how amounts of money are labelled
+type CurrencyDisplay int
+
+const (
+	// CurrencyCode labels amounts with their ISO 4217 code, e.g. 12.50 EUR
+	CurrencyCode CurrencyDisplay = iota
+	// CurrencySymbol labels amounts with their symbol, e.g. €12.50, or the code if there isn't one
+	CurrencySymbol
+	// CurrencyBoth labels amounts with their symbol and code, e.g. €12.50 (EUR)
+	CurrencyBoth
+)
+
+var currencyDisplayNames = map[string]CurrencyDisplay{
+	"code":   CurrencyCode,
+	"symbol": CurrencySymbol,
+	"both":   CurrencyBoth,
+}
+
+// currencyLocale is how a locale writes amounts of money
+type currencyLocale struct {
+	decimal, group string
+	// symbolAfter puts the symbol after the amount, with a space, e.g. 12,50 €
+	symbolAfter bool
+}
+
+// currencyLocales are the locales amounts can be written in, by BCP 47 tag.
+// The empty locale is plain numbers, with no grouping
+var currencyLocales = map[string]currencyLocale{
+	"":      {".", "", false},
+	"en":    {".", ",", false},
+	"de":    {",", ".", true},
+	"es":    {",", ".", true},
+	"it":    {",", ".", true},
+	"nl":    {",", ".", false},
+	"fr":    {",", "\u202f", true},
+	"sv":    {",", "\u00a0", true},
+	"de-CH": {".", "’", false},
+	"fr-CH": {",", "\u202f", true},
+}
+
+var (
+	currencyDisplay = CurrencyCode
+	currencyFormat  = currencyLocales[""]
+)
+
+// SetCurrencyFormat sets how amounts of money are written: display is code, symbol or both,
+// and locale is one of en, de, es, it, nl, fr, sv, de-CH or fr-CH, or empty for plain numbers
+func SetCurrencyFormat(display, locale string) error {
+	d, ok := currencyDisplayNames[strings.ToLower(display)]
+	if !ok {
+		return fmt.Errorf("unknown currency display %q, expected code, symbol or both", display)
+	}
+	l, ok := currencyLocales[canonicalLocale(locale)]
+	if !ok {
+		return fmt.Errorf("unknown currency locale %q", locale)
+	}
+	currencyDisplay, currencyFormat = d, l
+	return nil
+}
+
+// canonicalLocale writes a locale tag with a lower case language and upper case region, e.g. de_ch as de-CH
+func canonicalLocale(locale string) string {
+	language, region, ok := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	if !ok {
+		return strings.ToLower(language)
+	}
+	return strings.ToLower(language) + "-" + strings.ToUpper(region)
+}
+
+// currencyMinorUnits are the ISO 4217 minor units of the currencies that aren't divided into hundredths
+var currencyMinorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// minorUnits is how many decimal places a currency is written with, 2 unless ISO 4217 says otherwise
+func minorUnits(code string) int {
+	if n, ok := currencyMinorUnits[code]; ok {
+		return n
+	}
+	return 2
+}
+
+// currencySymbols are the symbols of common currencies. Symbols several currencies use, like $ and kr,
+// are prefixed for all of them, e.g. US$ and CA$, so amounts aren't ambiguous in servers around the world
+var currencySymbols = map[string]string{
+	"USD": "US$", "EUR": "€", "JPY": "¥", "GBP": "£", "CNY": "CN¥", "INR": "₹", "KRW": "₩",
+	"RUB": "₽", "CAD": "CA$", "AUD": "A$", "NZD": "NZ$", "HKD": "HK$", "SGD": "S$", "MXN": "MX$",
+	"BRL": "R$", "CHF": "Fr.", "ILS": "₪", "TRY": "₺", "PLN": "zł", "SEK": "SEK kr", "NOK": "NOK kr",
+	"DKK": "DKK kr", "THB": "฿", "PHP": "₱", "VND": "₫", "UAH": "₴", "NGN": "₦", "ZAR": "R",
+	"BTC": "₿", "ETH": "Ξ",
+}
+
+// formatMoney writes an amount to a number of decimal places, with the configured label and locale.
+// Trailing zeros are trimmed if trim is set, e.g. for crypto which has many places
+func formatMoney(v float64, places int, trim bool, code string) string {
+	number := strconv.FormatFloat(math.Abs(v), 'f', places, 64)
+	if trim && strings.Contains(number, ".") {
+		number = strings.TrimRight(strings.TrimRight(number, "0"), ".")
+	}
+	sign := ""
+	if v < 0 && strings.ContainsAny(number, "123456789") {
+		sign = "-"
+	}
+	number = groupDigits(number, currencyFormat)
+
+	symbol, ok := currencySymbols[code]
+	if currencyDisplay == CurrencyCode || !ok {
+		return fmt.Sprintf("%s%s %s", sign, number, code)
+	}
+	var s string
+	if currencyFormat.symbolAfter {
+		s = fmt.Sprintf("%s%s %s", sign, number, symbol)
+	} else if last, _ := utf8.DecodeLastRuneInString(symbol); unicode.IsLetter(last) {
+		// Symbols ending in a letter are kept apart from the number, e.g. SEK kr 12.50
+		s = fmt.Sprintf("%s%s %s", sign, symbol, number)
+	} else {
+		s = fmt.Sprintf("%s%s%s", sign, symbol, number)
+	}
+	if currencyDisplay == CurrencyBoth {
+		s += " (" + code + ")"
+	}
+	return s
+}
+
+// groupDigits rewrites a plain number, e.g. 1234.50, with a locale's separators, e.g. 1.234,50
+func groupDigits(number string, locale currencyLocale) string {
+	whole, fraction, hasFraction := strings.Cut(number, ".")
+	if locale.group != "" {
+		var grouped strings.Builder
+		for i, digit := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				grouped.WriteString(locale.group)
+			}
+			grouped.WriteRune(digit)
+		}
+		whole = grouped.String()
+	}
+	if !hasFraction {
+		return whole
+	}
+	return whole + locale.decimal + fraction
+}
@@ -0,0 +1,80 @@
+package convert
+
+import "testing"
+
+func TestFormatMoney(t *testing.T) {
+	defer SetCurrencyFormat("code", "")
+
+	tests := []struct {
+		display, locale string
+		v               float64
+		code            string
+		want            string
+	}{
+		{"code", "", 1234.5, "USD", "1234.50 USD"},
+		{"code", "en", -1234567.891, "USD", "-1,234,567.89 USD"},
+		{"symbol", "en", 1234.5, "USD", "US$1,234.50"},
+		{"symbol", "en", 1234.5, "SEK", "SEK kr 1,234.50"},
+		{"symbol", "en", 1234.5, "XYZ", "1,234.50 XYZ"},
+		{"both", "en", 1234.5, "EUR", "€1,234.50 (EUR)"},
+		{"symbol", "de", 1234.5, "EUR", "1.234,50 €"},
+		{"symbol", "fr", 1234.5, "EUR", "1\u202f234,50 €"},
+		{"symbol", "de-CH", 1234.5, "USD", "US$1’234.50"},
+		{"symbol", "de_ch", 1234.5, "CHF", "Fr.1’234.50"},
+		{"symbol", "fr-CH", 1234.5, "USD", "1\u202f234,50 US$"},
+		// Amounts that round to zero aren't negative
+		{"code", "", -0.001, "USD", "0.00 USD"},
+		// Currencies are written to their ISO 4217 minor units
+		{"code", "en", 1234.56, "JPY", "1,235 JPY"},
+		{"symbol", "en", 1234.5678, "KWD", "1,234.568 KWD"},
+		{"symbol", "de", 1234.56, "JPY", "1.235 ¥"},
+	}
+	for _, tt := range tests {
+		if err := SetCurrencyFormat(tt.display, tt.locale); err != nil {
+			t.Fatalf("SetCurrencyFormat(%q, %q) error = %v", tt.display, tt.locale, err)
+		}
+		if got := formatMoney(tt.v, minorUnits(tt.code), false, tt.code); got != tt.want {
+			t.Errorf("formatMoney(%v, %s) in %s %s = %q, want %q", tt.v, tt.code, tt.display, tt.locale, got, tt.want)
+		}
+	}
+
+	// Crypto amounts have their trailing zeros trimmed
+	SetCurrencyFormat("symbol", "en")
+	if got := formatMoney(0.12345, 8, true, "BTC"); got != "₿0.12345" {
+		t.Errorf("formatMoney(0.12345, BTC) = %q, want %q", got, "₿0.12345")
+	}
+
+	if err := SetCurrencyFormat("emoji", "en"); err == nil {
+		t.Error("SetCurrencyFormat(emoji) error = nil, want an error")
+	}
+	if err := SetCurrencyFormat("code", "xx"); err == nil {
+		t.Error("SetCurrencyFormat(code, xx) error = nil, want an error")
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	tests := []struct {
+		number, locale, want string
+	}{
+		{"1234567", "", "1234567"},
+		{"1234567", "en", "1,234,567"},
+		{"123", "en", "123"},
+		{"123456.75", "en", "123,456.75"},
+		{"1234.5", "de", "1.234,5"},
+		{"1234.5", "fr", "1\u202f234,5"},
+		{"1234567.5", "de-CH", "1’234’567.5"},
+	}
+	for _, tt := range tests {
+		if got := groupDigits(tt.number, currencyLocales[tt.locale]); got != tt.want {
+			t.Errorf("groupDigits(%q, %s) = %q, want %q", tt.number, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	for code, want := range map[string]int{"USD": 2, "EUR": 2, "JPY": 0, "KRW": 0, "KWD": 3, "BHD": 3, "CLF": 4} {
+		if got := minorUnits(code); got != want {
+			t.Errorf("minorUnits(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package convert
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CurrencyState is how loading the currencies is going
+type CurrencyState int
+
+const (
+	// CurrenciesLoading means the currencies haven't been loaded yet
+	CurrenciesLoading CurrencyState = iota
+	// CurrenciesReady means the currencies were loaded from a rate provider
+	CurrenciesReady
+	// CurrenciesDegraded means the last load failed, so only stored currencies are available if any, and it's being retried
+	CurrenciesDegraded
+)
+
+var currencyStateNames = map[CurrencyState]string{
+	CurrenciesLoading:  "loading",
+	CurrenciesReady:    "ready",
+	CurrenciesDegraded: "degraded",
+}
+
+func (s CurrencyState) String() string {
+	return currencyStateNames[s]
+}
+
+var (
+	// currencyRetryMin and currencyRetryMax bound the backoff between failed loads
+	currencyRetryMin = time.Second
+	currencyRetryMax = 5 * time.Minute
+	// currencyRefresh is how often the currency list is reloaded once it has loaded
+	currencyRefresh = 24 * time.Hour
+	// currencyAwaitMax is the longest a lookup waits for the first load, after which it carries on without currencies
+	currencyAwaitMax = 10 * time.Second
+
+	currencyLoaderOnce sync.Once
+	currencyFirstLoad  = make(chan struct{})
+	currencyStateLock  sync.RWMutex
+	currencyState      CurrencyState
+	currencyErr        error
+)
+
+// LoadCurrencies starts loading the currencies in the background, retrying until it works
+// and then reloading them periodically
+func LoadCurrencies() {
+	startCurrencyLoader()
+}
+
+// awaitCurrencies starts loading the currencies if it hasn't started, and waits for the first attempt
+// for up to currencyAwaitMax
+func awaitCurrencies() {
+	select {
+	case <-startCurrencyLoader():
+	case <-time.After(currencyAwaitMax):
+	}
+}
+
+// startCurrencyLoader starts the loader once, returning a channel closed after its first attempt
+func startCurrencyLoader() <-chan struct{} {
+	currencyLoaderOnce.Do(func() {
+		go runCurrencyLoader()
+	})
+	return currencyFirstLoad
+}
+
+func runCurrencyLoader() {
+	first := true
+	delay := currencyRetryMin
+	for {
+		err := loadCurrencies()
+		setCurrencyState(err)
+		if first {
+			close(currencyFirstLoad)
+			first = false
+		}
+
+		switch {
+		case err == nil:
+			delay = currencyRetryMin
+			time.Sleep(currencyRefresh)
+		case rateProvider == nil:
+			// There's nowhere to load them from, so retrying won't help
+			return
+		default:
+			slog.Warn("Retrying loading currencies", "in", delay, "err", err)
+			time.Sleep(delay)
+			delay *= 2
+			if delay > currencyRetryMax {
+				delay = currencyRetryMax
+			}
+		}
+	}
+}
+
+func setCurrencyState(err error) {
+	currencyStateLock.Lock()
+	defer currencyStateLock.Unlock()
+	currencyErr = err
+	if err == nil {
+		currencyState = CurrenciesReady
+	} else {
+		currencyState = CurrenciesDegraded
+	}
+}
+
+// CurrencyStatus is how loading the currencies is going, with the error of the last load if it failed
+func CurrencyStatus() (CurrencyState, error) {
+	currencyStateLock.RLock()
+	defer currencyStateLock.RUnlock()
+	return currencyState, currencyErr
+}
+
+// currencyCode matches names that could be currency codes, e.g. USD
+var currencyCode = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
+// isoCurrencyCodes are the ISO 4217 currency codes, so a code that isn't loaded can be told apart from a typo
+var isoCurrencyCodes = map[string]bool{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV BRL BSD BTN BWP BYN BZD
+		CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP
+		GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF
+		KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR
+		MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK
+		SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI
+		UYU UYW UZS VED VES VND VUV WST XAF XAG XAU XCD XCG XDR XOF XPD XPF XPT XSU XUA YER ZAR ZMW ZWG ZWL`) {
+		isoCurrencyCodes[code] = true
+	}
+}
+
+// mightBeCurrency checks whether a unit that wasn't found could be a currency that isn't loaded
+func mightBeCurrency(name string) bool {
+	if currencyCode.MatchString(name) {
+		code := strings.ToUpper(name)
+		if _, ok := storedCurrencies()[code]; ok || isoCurrencyCodes[code] {
+			return true
+		}
+	}
+	if _, ok := lookupCryptoAsset(strings.ToUpper(name)); ok {
+		return true
+	}
+	if strings.ContainsAny(name, "$€¥£") {
+		return true
+	}
+	if _, ok := ambiguousSymbol(name); ok {
+		return true
+	}
+	for _, aliases := range extraAliases {
+		for _, alias := range aliases {
+			if strings.EqualFold(alias, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// currenciesUnavailable checks whether the currencies can't be used because loading them is failing or hasn't finished,
+// rather than because there are no rate providers
+func currenciesUnavailable() bool {
+	state, _ := CurrencyStatus()
+	return rateProvider != nil && state != CurrenciesReady
+}
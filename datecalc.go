@@ -0,0 +1,278 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	p "unit-bot/parser"
+)
+
+// sumCommand adds and subtracts values, e.g. 2026-12-25 - today to days, or today + 90 days
+type sumCommand struct {
+	first any
+	terms []calcTerm
+	to    string
+}
+
+var (
+	addOp    = p.Token(`[+\-−]`)
+	sumTerms = p.Many(p.Parse2(addOp, fromExpr, func(op string, v any) calcTerm { return calcTerm{op, v} }))
+	sumExpr  = p.Parse3(fromExpr, sumTerms.Filter(func(ts []calcTerm) bool { return len(ts) > 0 }), target.Or(""), mapSum)
+)
+
+func mapSum(first any, terms []calcTerm, to string) sumCommand {
+	return sumCommand{first, terms, to}
+}
+
+func (cmd sumCommand) run(scope Scope) string {
+	acc, err := resolveValue(scope, cmd.first)
+	if err != nil {
+		return err.Error()
+	}
+
+	var expr strings.Builder
+	expr.WriteString(acc.String())
+	// span is the dates the result is the time between, so it can be converted to calendar months and years
+	var span []time.Time
+	units := []UnitType{acc.Unit()}
+	for _, t := range cmd.terms {
+		v, err := resolveValue(scope, t.val)
+		if err != nil {
+			return err.Error()
+		}
+		// Months and years added to dates are calendar months and years, not averages
+		if _, ok := acc.(DateVal); !ok {
+			units = append(units, v.Unit())
+		}
+
+		span = nil
+		if t.op == "+" {
+			fmt.Fprintf(&expr, " + %s", v)
+			acc, err = acc.Add(v)
+		} else {
+			fmt.Fprintf(&expr, " − %s", v)
+			if from, ok := v.(DateVal); ok {
+				if to, ok := acc.(DateVal); ok {
+					span = []time.Time{from.T, to.T}
+				}
+			}
+			acc, err = acc.Sub(v)
+		}
+		if err != nil {
+			return err.Error()
+		}
+	}
+
+	if name, depth, _ := strings.Cut(strings.ToLower(cmd.to), ":"); name == "human" && span != nil {
+		// The time between dates is broken into calendar months and years
+		n, err := strconv.Atoi(depth)
+		if err != nil {
+			n = len(calendarParts)
+		}
+		return fmt.Sprintf("%s = %s", expr.String(), describeSpan(span[0], span[1], n))
+	}
+	if target, ok := lookupTarget(cmd.to); ok {
+		reply, err := target(acc)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("%s = %s", expr.String(), reply)
+	}
+	if cmd.to != "" {
+		toUnit, ok := lookupUnitIn(scope, cmd.to)
+		if !ok {
+			return ErrorInvalidUnit{cmd.to}.Error()
+		}
+		if n, ok := calendarBetween(span, toUnit); ok {
+			acc = toUnit.FromFloat(n)
+		} else {
+			if acc, err = acc.Convert(toUnit); err != nil {
+				return err.Error()
+			}
+			units = append(units, toUnit)
+		}
+	}
+
+	return annotate(fmt.Sprintf("%s = %s", expr.String(), acc), units...)
+}
+
+// maxDateShift is the furthest in seconds a date can be moved, about 10000 years,
+// well within what time.Time and the calendar can represent
+const maxDateShift = 10000 * 365.2425 * 24 * 60 * 60
+
+// ErrorDateOutOfRange is returned when a date is moved too far to be represented
+var ErrorDateOutOfRange = errors.New("Dates can only be moved by up to 10000 years")
+
+// addDuration moves a time by sign times a duration.
+// Whole days, weeks, months and years are added on the calendar, so a month after Jan 31 is the end of February
+func addDuration(t time.Time, d UnitVal, sign int) (time.Time, error) {
+	if uv, ok := d.(UncertainVal); ok {
+		d = uv.Val
+	}
+	if d.Unit().Dimension() != UnitDimensionDuration {
+		return time.Time{}, ErrorConversion{d.Unit(), Second}
+	}
+	secs, err := d.Convert(Second)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if s := math.Abs(magnitude(secs)); s > maxDateShift || math.IsNaN(s) {
+		return time.Time{}, ErrorDateOutOfRange
+	}
+
+	if n := magnitude(d) * float64(sign); n == math.Trunc(n) {
+		switch d.Unit() {
+		case UnitType(Year):
+			return addMonths(t, 12*int(n)), nil
+		case UnitType(Month):
+			return addMonths(t, int(n)), nil
+		case UnitType(Week):
+			return t.AddDate(0, 0, 7*int(n)), nil
+		case UnitType(Day):
+			return t.AddDate(0, 0, int(n)), nil
+		}
+	}
+
+	// Whole seconds and nanoseconds separately, since a time.Duration overflows after about 292 years
+	whole, frac := math.Modf(magnitude(secs) * float64(sign))
+	return time.Unix(t.Unix()+int64(whole), int64(t.Nanosecond())+int64(frac*1e9)).In(t.Location()), nil
+}
+
+// addMonths adds calendar months to a time, keeping to the last day of shorter months
+func addMonths(t time.Time, n int) time.Time {
+	year, month, day := t.Date()
+	hour, minute, sec := t.Clock()
+	lastDay := time.Date(year, month+time.Month(n)+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month+time.Month(n), day, hour, minute, sec, t.Nanosecond(), t.Location())
+}
+
+// durationBetween is the time from one time to another, in the largest unit that fits
+func durationBetween(from, to time.Time) UnitVal {
+	secs := Second.FromFloat(to.Sub(from).Seconds())
+	for _, u := range []*DurationUnit{Day, Hour, Minute} {
+		if v, err := secs.Convert(u); err == nil && math.Abs(magnitude(v)) >= 1 {
+			return v
+		}
+	}
+	return secs
+}
+
+// calendarBetween counts the calendar months or years between the dates of a span,
+// with the fraction of the month or year the span ends in
+func calendarBetween(span []time.Time, u UnitType) (float64, bool) {
+	if len(span) != 2 {
+		return 0, false
+	}
+	var step func(time.Time, int) time.Time
+	switch u {
+	case UnitType(Month):
+		step = addMonths
+	case UnitType(Year):
+		step = func(t time.Time, n int) time.Time { return addMonths(t, 12*n) }
+	default:
+		return 0, false
+	}
+
+	from, to := span[0], span[1]
+	sign := 1.0
+	if to.Before(from) {
+		from, to, sign = to, from, -1
+	}
+	n := 0
+	for !step(from, n+1).After(to) {
+		n++
+	}
+	start, end := step(from, n), step(from, n+1)
+	return sign * (float64(n) + to.Sub(start).Seconds()/end.Sub(start).Seconds()), true
+}
+
+// calendarParts are the parts of a span of time, in calendar order
+var calendarParts = []struct {
+	name string
+	step func(time.Time, int) time.Time
+}{
+	{"year", func(t time.Time, n int) time.Time { return addMonths(t, 12*n) }},
+	{"month", addMonths},
+	{"day", func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }},
+	{"hour", func(t time.Time, n int) time.Time { return t.Add(time.Duration(n) * time.Hour) }},
+	{"minute", func(t time.Time, n int) time.Time { return t.Add(time.Duration(n) * time.Minute) }},
+	{"second", func(t time.Time, n int) time.Time { return t.Add(time.Duration(n) * time.Second) }},
+}
+
+// describeSpan writes the time between two times in at most n calendar years, months, days, hours and minutes,
+// e.g. 2 months, 13 days and 4 hours
+func describeSpan(from, to time.Time, n int) string {
+	if to.Before(from) {
+		from, to = to, from
+	}
+	var parts []string
+	for _, part := range calendarParts {
+		if len(parts) == n {
+			break
+		}
+		count := 0
+		for !part.step(from, count+1).After(to) {
+			count++
+		}
+		from = part.step(from, count)
+		// Seconds only matter when it's less than a minute
+		if count == 0 || (part.name == "second" && len(parts) > 0) {
+			continue
+		}
+		parts = append(parts, plural(count, part.name))
+	}
+
+	switch len(parts) {
+	case 0:
+		return "no time"
+	case 1:
+		return parts[0]
+	default:
+		return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+	}
+}
+
+// plural writes a count of something, e.g. 1 day or 2 days
+func plural(n int, name string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, name)
+	}
+	return fmt.Sprintf("%d %ss", n, name)
+}
+
+// Until says how long it is until a date, e.g. !until 2027-01-01 00:00 UTC
+func Until(scope Scope, when string) string {
+	var t time.Time
+	if v, n, ok := p.First(dateVal, relativeDate)([]byte(when)); ok && strings.TrimSpace(when[n:]) == "" {
+		date, err := resolveValue(scope, v)
+		if err != nil {
+			return err.Error()
+		}
+		t = date.(DateVal).T
+	} else if zt, n, ok := zonedTimeExpr([]byte(when)); ok && strings.TrimSpace(when[n:]) == "" {
+		var err error
+		if t, err = zt.resolve(scope); err != nil {
+			return err.Error()
+		}
+	} else {
+		return "Usage: !until [date] [time] [zone], e.g. 2027-01-01 00:00 UTC"
+	}
+
+	date := DateVal{t, DateTime}
+	now := time.Now()
+	switch {
+	case t.After(now):
+		return fmt.Sprintf("%s is in %s (<t:%d:R>)", date, describeSpan(now, t, len(calendarParts)), t.Unix())
+	case t.Before(now):
+		return fmt.Sprintf("%s was %s ago (<t:%d:R>)", date, describeSpan(t, now, len(calendarParts)), t.Unix())
+	default:
+		return fmt.Sprintf("%s is now", date)
+	}
+}
@@ -0,0 +1,16 @@
+package convert
+
+import "testing"
+
+func TestDateArithmetic(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"2026-12-25 - 2026-10-19 to days", "Fri Dec 25 2026 − Mon Oct 19 2026 = 67 days"},
+		{"2024-03-01 + 90 days", "Fri Mar 1 2024 + 90 days = Thu May 30 2024"},
+		// Months are calendar months, keeping to the end of shorter months
+		{"2024-01-31 + 1 month", "Wed Jan 31 2024 + 1 months = Thu Feb 29 2024"},
+		{"2024-01-01 - 2020-01-01 to years", "Mon Jan 1 2024 − Wed Jan 1 2020 = 4 years"},
+		{"2024-01-01 - 2022-03-15 to human", "Mon Jan 1 2024 − Tue Mar 15 2022 = 1 year, 9 months and 17 days"},
+		{"today + 20000 years", "Dates can only be moved by up to 10000 years"},
+		{"2024-03-01 + 5 kg", "Can't add date and kg"},
+	})
+}
@@ -0,0 +1,301 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Dims are the exponents of the SI base dimensions m, kg, s, A, K, mol and cd
+type Dims [7]int
+
+var baseSymbols = [len(Dims{})]string{"m", "kg", "s", "A", "K", "mol", "cd"}
+
+// dimensionDims are the SI base dimensions of each UnitDimension that has them
+var dimensionDims = map[UnitDimension]Dims{
+	UnitDimensionLength:      {1, 0, 0, 0, 0, 0, 0},
+	UnitDimensionMass:        {0, 1, 0, 0, 0, 0, 0},
+	UnitDimensionSpeed:       {1, 0, -1, 0, 0, 0, 0},
+	UnitDimensionDuration:    {0, 0, 1, 0, 0, 0, 0},
+	UnitDimensionTemperature: {0, 0, 0, 0, 1, 0, 0},
+	UnitDimensionVolume:      {3, 0, 0, 0, 0, 0, 0},
+
+	UnitDimensionForce:             {1, 1, -2, 0, 0, 0, 0},
+	UnitDimensionEnergy:            {2, 1, -2, 0, 0, 0, 0},
+	UnitDimensionPower:             {2, 1, -3, 0, 0, 0, 0},
+	UnitDimensionPressure:          {-1, 1, -2, 0, 0, 0, 0},
+	UnitDimensionFrequency:         {0, 0, -1, 0, 0, 0, 0},
+	UnitDimensionCurrent:           {0, 0, 0, 1, 0, 0, 0},
+	UnitDimensionAmount:            {0, 0, 0, 0, 0, 1, 0},
+	UnitDimensionLuminousIntensity: {0, 0, 0, 0, 0, 0, 1},
+}
+
+func (d Dims) add(o Dims, sign int) Dims {
+	for i := range d {
+		d[i] += sign * o[i]
+	}
+	return d
+}
+
+func (d Dims) pow(n int) Dims {
+	for i := range d {
+		d[i] *= n
+	}
+	return d
+}
+
+// Dimension returns the UnitDimension with the same SI base dimensions, or UnitDimensionNone
+func (d Dims) Dimension() UnitDimension {
+	for dim, dims := range dimensionDims {
+		if dims == d {
+			return dim
+		}
+	}
+	return UnitDimensionNone
+}
+
+// String writes the dimensions as SI base units, e.g. m^3/(kg·s^2)
+func (d Dims) String() string {
+	var num, den []string
+	for i, e := range d {
+		switch {
+		case e == 1:
+			num = append(num, baseSymbols[i])
+		case e > 1:
+			num = append(num, fmt.Sprintf("%s^%d", baseSymbols[i], e))
+		case e == -1:
+			den = append(den, baseSymbols[i])
+		case e < -1:
+			den = append(den, fmt.Sprintf("%s^%d", baseSymbols[i], -e))
+		}
+	}
+
+	s := strings.Join(num, "·")
+	switch {
+	case len(den) == 0:
+		return s
+	case len(num) == 0:
+		s = "1"
+	}
+	if len(den) > 1 {
+		return s + "/(" + strings.Join(den, "·") + ")"
+	}
+	return s + "/" + den[0]
+}
+
+// siDef defines a unit in SI base units, where a value x is Offset + Factor*x in SI
+type siDef struct {
+	Factor, Offset float64
+	Dims           Dims
+}
+
+// siUnit is implemented by units with a definition in SI base units
+type siUnit interface {
+	si() (siDef, bool)
+}
+
+func siDefOf(u UnitType) (siDef, bool) {
+	if u, ok := u.(siUnit); ok {
+		return u.si()
+	}
+	return siDef{}, false
+}
+
+// convertSI converts between any two units with the same SI base dimensions
+func convertSI(v UnitVal, to UnitType) (UnitVal, error) {
+	from, ok := siDefOf(v.Unit())
+	if !ok {
+		return nil, ErrorConversion{v.Unit(), to}
+	}
+	def, ok := siDefOf(to)
+	if !ok || from.Dims != def.Dims {
+		return nil, ErrorConversion{v.Unit(), to}
+	}
+	si := from.Offset + from.Factor*magnitude(v)
+	return to.FromFloat((si - def.Offset) / def.Factor), nil
+}
+
+// DerivedUnit is a unit defined directly in SI base units, e.g. N or m/s^2
+type DerivedUnit struct {
+	name string
+	def  siDef
+}
+
+func (u *DerivedUnit) String() string {
+	return u.name
+}
+
+func (u *DerivedUnit) Dimension() UnitDimension {
+	return u.def.Dims.Dimension()
+}
+
+func (u *DerivedUnit) FromFloat(f float64) UnitVal {
+	return Quantity{f, u}
+}
+
+func (u *DerivedUnit) si() (siDef, bool) {
+	return u.def, true
+}
+
+// siBaseUnit is the derived unit that is exactly SI base units with the given dimensions
+func siBaseUnit(dims Dims) *DerivedUnit {
+	return &DerivedUnit{dims.String(), siDef{Factor: 1, Dims: dims}}
+}
+
+// Derived SI units
+var (
+	Mole    = &DerivedUnit{"mol", siDef{Factor: 1, Dims: Dims{0, 0, 0, 0, 0, 1, 0}}}
+	Newton  = &DerivedUnit{"N", siDef{Factor: 1, Dims: Dims{1, 1, -2, 0, 0, 0, 0}}}
+	Joule   = &DerivedUnit{"J", siDef{Factor: 1, Dims: Dims{2, 1, -2, 0, 0, 0, 0}}}
+	Watt    = &DerivedUnit{"W", siDef{Factor: 1, Dims: Dims{2, 1, -3, 0, 0, 0, 0}}}
+	Pascal  = &DerivedUnit{"Pa", siDef{Factor: 1, Dims: Dims{-1, 1, -2, 0, 0, 0, 0}}}
+	Hertz   = &DerivedUnit{"Hz", siDef{Factor: 1, Dims: Dims{0, 0, -1, 0, 0, 0, 0}}}
+	Ampere  = &DerivedUnit{"A", siDef{Factor: 1, Dims: Dims{0, 0, 0, 1, 0, 0, 0}}}
+	Candela = &DerivedUnit{"cd", siDef{Factor: 1, Dims: Dims{0, 0, 0, 0, 0, 0, 1}}}
+)
+
+// Quantity is a value in a derived unit
+type Quantity struct {
+	V float64
+	U *DerivedUnit
+}
+
+// Convert implements UnitVal conversion
+func (q Quantity) Convert(to UnitType) (UnitVal, error) {
+	return convertSI(q, to)
+}
+
+func (q Quantity) Unit() UnitType {
+	return q.U
+}
+
+func (q Quantity) Add(other UnitVal) (UnitVal, error) {
+	return combine("add", q, other, 1)
+}
+
+func (q Quantity) Sub(other UnitVal) (UnitVal, error) {
+	return combine("subtract", q, other, -1)
+}
+
+func (q Quantity) Mul(k float64) (UnitVal, error) {
+	return Quantity{q.V * k, q.U}, nil
+}
+
+func (q Quantity) Div(k float64) (UnitVal, error) {
+	if k == 0 {
+		return nil, ErrorDivideByZero
+	}
+	return Quantity{q.V / k, q.U}, nil
+}
+
+func (q Quantity) Neg() (UnitVal, error) {
+	return Quantity{-q.V, q.U}, nil
+}
+
+func (q Quantity) Abs() (UnitVal, error) {
+	return Quantity{math.Abs(q.V), q.U}, nil
+}
+
+func (q Quantity) Cmp(other UnitVal) (int, error) {
+	return compare(q, other)
+}
+
+func (q Quantity) float() float64 {
+	return q.V
+}
+
+func (q Quantity) String() string {
+	if q.U.name == "" {
+		return fmt.Sprintf("%.6g", q.V)
+	}
+	return simpleUnitString(q.V, q.U)
+}
+
+// Multiply multiplies two values into a quantity in SI base units.
+// Relative uncertainties of the operands are combined in quadrature
+func Multiply(a, b UnitVal) (UnitVal, error) {
+	return product("multiply", a, b, 1)
+}
+
+// Divide divides two values into a quantity in SI base units.
+// Relative uncertainties of the operands are combined in quadrature
+func Divide(a, b UnitVal) (UnitVal, error) {
+	return product("divide", a, b, -1)
+}
+
+func product(op string, a, b UnitVal, sign int) (UnitVal, error) {
+	da, okA := siDefOf(a.Unit())
+	db, okB := siDefOf(b.Unit())
+	if !okA || !okB {
+		return nil, ErrorIncompatible{op, a.Unit(), b.Unit()}
+	}
+
+	x := da.Offset + da.Factor*magnitude(a)
+	y := db.Offset + db.Factor*magnitude(b)
+	v := x * y
+	if sign < 0 {
+		if y == 0 {
+			return nil, ErrorDivideByZero
+		}
+		v = x / y
+	}
+	q := Quantity{v, siBaseUnit(da.Dims.add(db.Dims, sign))}
+
+	rel := math.Hypot(relative(a), relative(b))
+	if rel != 0 {
+		return UncertainVal{q, math.Abs(v * rel)}, nil
+	}
+	return q, nil
+}
+
+// relative returns the relative uncertainty of a value, which is 0 for exact values
+func relative(v UnitVal) float64 {
+	if uv, ok := v.(UncertainVal); ok {
+		return uv.Relative()
+	}
+	return 0
+}
+
+var superscripts = strings.NewReplacer("²", "^2", "³", "^3")
+
+// parseCompoundUnit parses a product of units with powers, e.g. m/s^2 or J/(mol·K).
+// Every unit after a / is in the denominator
+func parseCompoundUnit(s string) (*DerivedUnit, bool) {
+	expr := superscripts.Replace(s)
+	expr = strings.NewReplacer("(", "", ")", "", "*", "·").Replace(expr)
+
+	def := siDef{Factor: 1}
+	sign := 1
+	for i, part := range strings.Split(expr, "/") {
+		if i > 0 {
+			sign = -1
+		}
+		for _, factor := range strings.Split(part, "·") {
+			name, power := factor, 1
+			if base, exp, ok := strings.Cut(factor, "^"); ok {
+				n, err := strconv.Atoi(exp)
+				if err != nil {
+					return nil, false
+				}
+				name, power = base, n
+			}
+			if name == "1" && i == 0 {
+				continue
+			}
+
+			// Offsets don't apply to units within a product, e.g. J/°C is J/K
+			u, ok := lookupNamedUnit(name)
+			if !ok {
+				return nil, false
+			}
+			d, ok := siDefOf(u)
+			if !ok {
+				return nil, false
+			}
+			def.Factor *= math.Pow(d.Factor, float64(sign*power))
+			def.Dims = def.Dims.add(d.Dims.pow(power), sign)
+		}
+	}
+	return &DerivedUnit{s, def}, true
+}
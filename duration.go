@@ -1,17 +1,54 @@
 package convert
 
-import "github.com/martinlindhe/unit"
+import (
+	"strings"
+
+	"github.com/martinlindhe/unit"
+)
 
 // DurationUnit is a unit of time
 type DurationUnit = SimpleUnit[unit.Duration]
 
-// Time units
+// Lengths of months and years that aren't in the unit package
+const (
+	gregorianYear = unit.Day * 365.2425
+	calendarMonth = gregorianYear / 12
+	siderealYear  = unit.Day * 365.256363004
+	tropicalYear  = unit.Day * 365.24219
+)
+
+// Time units.
+// Months and years are calendar averages by default, so they match calendar arithmetic on dates
 var (
-	Second = &DurationUnit{UnitDimensionDuration, "s", from(unit.Second), unit.Duration.Seconds}
-	Minute = &DurationUnit{UnitDimensionDuration, "min", from(unit.Minute), unit.Duration.Minutes}
-	Hour   = &DurationUnit{UnitDimensionDuration, "hr", from(unit.Hour), unit.Duration.Hours}
-	Day    = &DurationUnit{UnitDimensionDuration, "days", from(unit.Day), unit.Duration.Days}
-	Week   = &DurationUnit{UnitDimensionDuration, "weeks", from(unit.Week), unit.Duration.Weeks}
-	Month  = &DurationUnit{UnitDimensionDuration, "months", from(unit.ThirtyDayMonth), unit.Duration.ThirtyDayMonths}
-	Year   = &DurationUnit{UnitDimensionDuration, "years", from(unit.JulianYear), unit.Duration.JulianYears}
+	Second         = &DurationUnit{UnitDimensionDuration, "s", from(unit.Second), unit.Duration.Seconds}
+	Minute         = &DurationUnit{UnitDimensionDuration, "min", from(unit.Minute), unit.Duration.Minutes}
+	Hour           = &DurationUnit{UnitDimensionDuration, "hr", from(unit.Hour), unit.Duration.Hours}
+	Day            = &DurationUnit{UnitDimensionDuration, "days", from(unit.Day), unit.Duration.Days}
+	Week           = &DurationUnit{UnitDimensionDuration, "weeks", from(unit.Week), unit.Duration.Weeks}
+	Month          = &DurationUnit{UnitDimensionDuration, "months", from(calendarMonth), inUnitsOf(calendarMonth)}
+	ThirtyDayMonth = &DurationUnit{UnitDimensionDuration, "30-day months", from(unit.ThirtyDayMonth), unit.Duration.ThirtyDayMonths}
+	Year           = &DurationUnit{UnitDimensionDuration, "years", from(gregorianYear), inUnitsOf(gregorianYear)}
+	JulianYear     = &DurationUnit{UnitDimensionDuration, "Julian years", from(unit.JulianYear), unit.Duration.JulianYears}
+	SiderealYear   = &DurationUnit{UnitDimensionDuration, "sidereal years", from(siderealYear), inUnitsOf(siderealYear)}
+	TropicalYear   = &DurationUnit{UnitDimensionDuration, "tropical years", from(tropicalYear), inUnitsOf(tropicalYear)}
 )
+
+// unitNotes explain units whose definition people might not expect
+var unitNotes = map[UnitType]string{
+	Month: "A month is a calendar-average month of 30.436875 days. Use month30 for 30-day months",
+	Year:  "A year is a Gregorian year of 365.2425 days. Julian, sidereal and tropical years are julianyear, siderealyear and tropicalyear",
+}
+
+// annotate adds the notes of the units used in a reply, once each
+func annotate(reply string, units ...UnitType) string {
+	seen := map[UnitType]bool{}
+	var notes strings.Builder
+	for _, u := range units {
+		if note, ok := unitNotes[u]; ok && !seen[u] {
+			seen[u] = true
+			notes.WriteString("\n-# ")
+			notes.WriteString(note)
+		}
+	}
+	return reply + notes.String()
+}
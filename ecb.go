@@ -0,0 +1,96 @@
+package convert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ecbDailyURL is the European Central Bank's feed of the day's reference rates
+const ecbDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// ECBProvider gets the daily euro reference rates of the European Central Bank.
+// They are published once a working day, for about 30 currencies
+type ECBProvider struct {
+	// URL is the daily XML feed, ecbDailyURL if empty
+	URL    string
+	Client *http.Client
+}
+
+// ecbEnvelope is the XML of the ECB feed:
+//
+//	<Cube><Cube time="2024-03-01"><Cube currency="USD" rate="1.0830"/>...</Cube></Cube>
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func (e ECBProvider) Name() string {
+	u, err := url.Parse(e.url())
+	if err != nil {
+		return e.url()
+	}
+	return u.Host
+}
+
+func (e ECBProvider) url() string {
+	if e.URL == "" {
+		return ecbDailyURL
+	}
+	return e.URL
+}
+
+// latest gets the most recent day of rates in the feed, from euros
+func (e ECBProvider) latest() (Rates, error) {
+	body, err := getBody(e.Client, e.url())
+	if err != nil {
+		return Rates{}, err
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return Rates{}, fmt.Errorf("unable to decode ECB rates: %w", err)
+	}
+	if len(envelope.Days) == 0 {
+		return Rates{}, fmt.Errorf("no rates in ECB feed")
+	}
+
+	day := envelope.Days[0]
+	published, err := time.Parse("2006-01-02", day.Time)
+	if err != nil {
+		return Rates{}, fmt.Errorf("unable to decode ECB rate date: %w", err)
+	}
+	rates := Rates{Base: "EUR", Rates: make(map[string]float64, len(day.Rates)), Source: e.Name(), Time: published}
+	for _, r := range day.Rates {
+		rates.Rates[r.Currency] = r.Rate
+	}
+	return rates, nil
+}
+
+func (e ECBProvider) Currencies() (map[string]string, error) {
+	rates, err := e.latest()
+	if err != nil {
+		return nil, err
+	}
+	// The feed only has codes
+	currencies := map[string]string{"EUR": "EUR"}
+	for code := range rates.Rates {
+		currencies[code] = code
+	}
+	return currencies, nil
+}
+
+func (e ECBProvider) Rates(base string, symbols []string) (Rates, error) {
+	rates, err := e.latest()
+	if err != nil {
+		return Rates{}, err
+	}
+	return rates.rebase(base, symbols)
+}
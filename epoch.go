@@ -0,0 +1,265 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	p "unit-bot/parser"
+)
+
+// DateUnit is a way of writing a moment in time, e.g. as a Unix timestamp or ISO-8601 date
+type DateUnit struct {
+	name string
+	// perSecond is how many of the unit's numbers there are in a second, for numeric formats
+	perSecond float64
+	layout    string
+}
+
+// dateOnlyLayout is how dates at midnight are written, e.g. for today + 90 days
+const dateOnlyLayout = "Mon Jan 2 2006"
+
+// Date formats
+var (
+	Unix      = &DateUnit{name: "unix", perSecond: 1}
+	UnixMilli = &DateUnit{name: "unix ms", perSecond: 1000}
+	ISO8601   = &DateUnit{name: "ISO-8601", layout: time.RFC3339}
+	RFC1123   = &DateUnit{name: "RFC-1123", layout: time.RFC1123}
+	DateTime  = &DateUnit{name: "date", layout: "Mon Jan 2 2006 15:04:05 MST"}
+)
+
+func (u *DateUnit) String() string {
+	return u.name
+}
+
+func (u *DateUnit) Dimension() UnitDimension {
+	return UnitDimensionDate
+}
+
+// FromFloat makes a date from a number of the unit, or of seconds since the epoch for formats that aren't numeric
+func (u *DateUnit) FromFloat(f float64) UnitVal {
+	perSecond := u.perSecond
+	if perSecond == 0 {
+		perSecond = 1
+	}
+	sec, frac := math.Modf(f / perSecond)
+	return DateVal{time.Unix(int64(sec), int64(frac*1e9)).UTC(), u}
+}
+
+// DateVal is a moment in time written in a date format
+type DateVal struct {
+	T time.Time
+	U *DateUnit
+}
+
+func (dv DateVal) String() string {
+	if dv.U.layout == "" {
+		return fmt.Sprintf("%.13g %s", dv.float(), dv.U)
+	}
+	if hour, minute, sec := dv.T.Clock(); dv.U == DateTime && hour == 0 && minute == 0 && sec == 0 && dv.T.Nanosecond() == 0 {
+		return dv.T.Format(dateOnlyLayout)
+	}
+	t := dv.T
+	if dv.U == RFC1123 {
+		t = t.UTC()
+	}
+	return t.Format(dv.U.layout)
+}
+
+func (dv DateVal) Unit() UnitType {
+	return dv.U
+}
+
+// Convert writes the date in another format
+func (dv DateVal) Convert(to UnitType) (UnitVal, error) {
+	if to, ok := to.(*DateUnit); ok {
+		return DateVal{dv.T, to}, nil
+	}
+	return nil, ErrorConversion{dv.U, to}
+}
+
+// Add moves the date later by a duration, by calendar months and years for whole months and years
+func (dv DateVal) Add(other UnitVal) (UnitVal, error) {
+	t, err := addDuration(dv.T, other, 1)
+	if errors.Is(err, ErrorDateOutOfRange) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrorIncompatible{"add", dv.U, other.Unit()}
+	}
+	return DateVal{t, dv.U}, nil
+}
+
+// Sub moves the date earlier by a duration, or finds the time since another date
+func (dv DateVal) Sub(other UnitVal) (UnitVal, error) {
+	if o, ok := other.(DateVal); ok {
+		return durationBetween(o.T, dv.T), nil
+	}
+	t, err := addDuration(dv.T, other, -1)
+	if errors.Is(err, ErrorDateOutOfRange) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrorIncompatible{"subtract", dv.U, other.Unit()}
+	}
+	return DateVal{t, dv.U}, nil
+}
+
+// Mul, Div, Neg and Abs have no meaning for a moment in time
+func (dv DateVal) Mul(k float64) (UnitVal, error) {
+	return nil, ErrorIncompatible{Op: "multiply", A: dv.U}
+}
+
+func (dv DateVal) Div(k float64) (UnitVal, error) {
+	return nil, ErrorIncompatible{Op: "divide", A: dv.U}
+}
+
+func (dv DateVal) Neg() (UnitVal, error) {
+	return nil, ErrorIncompatible{Op: "negate", A: dv.U}
+}
+
+func (dv DateVal) Abs() (UnitVal, error) {
+	return nil, ErrorIncompatible{Op: "take the absolute value of", A: dv.U}
+}
+
+func (dv DateVal) Cmp(other UnitVal) (int, error) {
+	o, ok := other.(DateVal)
+	if !ok {
+		return 0, ErrorIncompatible{"compare", dv.U, other.Unit()}
+	}
+	switch {
+	case dv.T.Before(o.T):
+		return -1, nil
+	case dv.T.After(o.T):
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// float is the number of the unit since the epoch, or seconds for formats that aren't numeric
+func (dv DateVal) float() float64 {
+	perSecond := dv.U.perSecond
+	if perSecond == 0 {
+		perSecond = 1
+	}
+	// Seconds and nanoseconds separately, since UnixNano overflows after 2262
+	return (float64(dv.T.Unix()) + float64(dv.T.Nanosecond())/1e9) * perSecond
+}
+
+// isoLayouts and httpLayouts are the layouts dates can be written in, tried in order
+var (
+	isoLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02",
+	}
+	httpLayouts = []string{time.RFC1123, time.RFC1123Z}
+)
+
+var (
+	isoDate  = p.Token(`\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:\d{2}|\s+UTC\b|\s+GMT\b)?)?`)
+	httpDate = p.Token(`[A-Za-z]{3}, \d{2} [A-Za-z]{3} \d{4} \d{2}:\d{2}:\d{2} (?:[A-Z]{3,4}|[+-]\d{4})`)
+	dateVal  = p.Map(p.First(httpDate, isoDate), mapDate).Filter(func(v any) bool { return v != nil })
+	// relativeDate is a date relative to when the command is sent, e.g. today
+	relativeDate = p.Map(p.Token(`(?i)(now|today|tomorrow|yesterday)\b`), mapRelativeDate)
+	// epochVal is a Unix timestamp marked with an @, e.g. @1700000000, in seconds or with 13 digits in milliseconds.
+	// Bare numbers are only timestamps when converted to a date format, since they're more often phone numbers or IDs
+	epochVal  = p.Map(p.Token(`@\d{1,13}\b`), mapEpoch)
+	bareEpoch = p.Map(p.Token(`\d{9,13}\b`), mapEpoch)
+	// epochExpr converts a bare timestamp to a date format, e.g. 1700000000 to date
+	epochExpr = p.Parse3(bareEpoch, p.Atom(`to`), convertTo.Filter(toDates), func(v any, _ string, cmd command) command {
+		cmd.from = []any{v}
+		return cmd
+	})
+	// dateUnit is a date format with a space in its name, e.g. unix ms
+	dateUnit = p.Map(p.Token(`(?i)(unix|epoch)\s+(ms|millis|milliseconds)\b`), func(string) string { return "unixms" })
+)
+
+// ParseDate parses a date written in ISO-8601 or RFC-1123, assuming UTC if it has no time zone
+func ParseDate(s string) (time.Time, error) {
+	layouts := isoLayouts
+	if strings.Contains(s, ",") {
+		layouts = httpLayouts
+	} else {
+		s = strings.TrimSpace(s)
+		if trimmed := strings.TrimRight(strings.TrimSuffix(strings.TrimSuffix(s, "UTC"), "GMT"), " "); trimmed != s {
+			s = trimmed + "Z"
+		}
+		s = strings.Replace(s, " ", "T", 1)
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid date %s", s)
+}
+
+func mapDate(s string) any {
+	t, err := ParseDate(s)
+	if err != nil {
+		return nil
+	}
+	switch {
+	case strings.Contains(s, ","):
+		return DateVal{t, RFC1123}
+	case len(strings.TrimSpace(s)) == len("2006-01-02"):
+		return DateVal{t, DateTime}
+	default:
+		return DateVal{t, ISO8601}
+	}
+}
+
+// relativeDay is a date relative to when the command is sent, e.g. today.
+// It's resolved when the command runs, in the time zone of whoever sent it
+type relativeDay string
+
+func (d relativeDay) String() string {
+	return string(d)
+}
+
+func mapRelativeDate(s string) any {
+	return relativeDay(strings.ToLower(s))
+}
+
+// in resolves the date in a time zone, with today starting at its midnight
+func (d relativeDay) in(loc *time.Location) DateVal {
+	now := time.Now().In(loc)
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	switch d {
+	case "now":
+		return DateVal{now, DateTime}
+	case "tomorrow":
+		return DateVal{today.AddDate(0, 0, 1), DateTime}
+	case "yesterday":
+		return DateVal{today.AddDate(0, 0, -1), DateTime}
+	default:
+		return DateVal{today, DateTime}
+	}
+}
+
+// toDates is whether every unit a command converts to is a date format
+func toDates(cmd command) bool {
+	for _, to := range cmd.to {
+		if u, ok := LookupUnit(to); !ok || u.Dimension() != UnitDimensionDate {
+			return false
+		}
+	}
+	return len(cmd.to) > 0
+}
+
+func mapEpoch(s string) any {
+	s = strings.TrimPrefix(s, "@")
+	var v float64
+	fmt.Sscan(s, &v)
+	if len(s) == 13 {
+		return UnixMilli.FromFloat(v)
+	}
+	return Unix.FromFloat(v)
+}
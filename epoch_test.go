@@ -0,0 +1,17 @@
+package convert
+
+import "testing"
+
+func TestEpochs(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"1700000000 to date", "1700000000 unix = Tue Nov 14 2023 22:13:20 UTC"},
+		{"@1700000000 to date", "1700000000 unix = Tue Nov 14 2023 22:13:20 UTC"},
+		{"1700000000000 to ISO-8601", "1700000000000 unix ms = 2023-11-14T22:13:20Z"},
+		{"@1700000000 to unix ms", "1700000000 unix = 1700000000000 unix ms"},
+		{"2024-03-01T12:00Z to unix", "2024-03-01T12:00:00Z = 1709294400 unix"},
+		{"2024-03-01 to unix", "Fri Mar 1 2024 = 1709251200 unix"},
+		// Bare numbers are only timestamps when converted to a date
+		{"1700000000 to km", "Usage: !conv [amount][from-unit] to [to-unit]"},
+		{"12345 to date", "Usage: !conv [amount][from-unit] to [to-unit]"},
+	})
+}
@@ -0,0 +1,197 @@
+package convert
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	p "unit-bot/parser"
+)
+
+// explainCommand shows how a conversion is done, e.g. explain 5 ft to cm
+type explainCommand struct {
+	command
+}
+
+var explainExpr = p.Parse2(p.Atom(`explain`), convertExpr, func(_ string, cmd command) explainCommand { return explainCommand{cmd} })
+
+func (cmd explainCommand) run(scope Scope) string {
+	return explainAll(scope, cmd.from, cmd.to, cmd.on)
+}
+
+// Explain shows how values are converted to a unit
+func Explain(from, to string) string {
+	return ExplainOn(Scope{}, from, to, "")
+}
+
+// ExplainOn shows how values are converted to a unit, using the defaults of the guild and user it was sent from
+// and the exchange rates of a date
+func ExplainOn(scope Scope, from, to, on string) string {
+	values, _, ok := valueList([]byte(from))
+	if !ok {
+		slog.Info("Invalid command", "expr", from, "values", values)
+		return "Usage: !conv explain [amount][from-unit] to [to-unit]"
+	}
+
+	targets, n, ok := targetList([]byte(to))
+	if !ok || strings.TrimSpace(to[n:]) != "" {
+		targets = []string{to}
+	}
+
+	return explainAll(scope, values, targets, on)
+}
+
+func explainAll(scope Scope, values []any, to []string, on string) string {
+	var toUnits []UnitType
+	for _, name := range to {
+		toUnit, ok := lookupUnitIn(scope, name)
+		if !ok {
+			return ErrorInvalidUnit{name}.Error()
+		}
+		toUnits = append(toUnits, toUnit)
+	}
+	date, err := parseAsOf(on)
+	if err != nil {
+		return err.Error()
+	}
+
+	var lines []string
+	for _, v := range values {
+		from, err := resolveValue(scope, v)
+		if err != nil {
+			lines = append(lines, err.Error())
+			continue
+		}
+		for _, toUnit := range toUnits {
+			explanation, err := ExplainConversion(asOf(from, date), toUnit)
+			if err != nil {
+				explanation = err.Error()
+			}
+			lines = append(lines, explanation)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExplainConversion describes the factors or formula used to convert a value,
+// going through SI base units, e.g. 5 ft × 0.3048 m/ft = 1.524 m × 100 cm/m = 152.4 cm
+func ExplainConversion(from UnitVal, to UnitType) (string, error) {
+	result, err := from.Convert(to)
+	if err != nil {
+		return "", err
+	}
+
+	if uv, ok := from.(UncertainVal); ok {
+		from = uv.Val
+	}
+	if cv, ok := from.(CurrencyVal); ok {
+		return explainCurrency(cv, result)
+	}
+	if _, ok := from.(DateVal); ok {
+		return fmt.Sprintf("%s = %s", from, result), nil
+	}
+
+	// Feet + inches are explained as feet
+	var steps []string
+	if fi, ok := from.(FootInchVal); ok {
+		if fi.Inches != 0 {
+			steps = append(steps, fi.String())
+		}
+		from = fi.feet()
+	}
+	var footInch UnitVal
+	if to == UnitType(FootInch) {
+		footInch, to = result, Foot
+		if result, err = from.Convert(Foot); err != nil {
+			return "", err
+		}
+	}
+
+	fromDef, ok := siDefOf(from.Unit())
+	if !ok {
+		return "", ErrorConversion{from.Unit(), to}
+	}
+	toDef, ok := siDefOf(to)
+	if !ok {
+		return "", ErrorConversion{from.Unit(), to}
+	}
+
+	base := fromDef.Dims.String()
+	x := magnitude(from)
+	si := fromDef.Offset + fromDef.Factor*x
+	if from.Unit().String() != base {
+		steps = append(steps, explainToSI(x, from.Unit().String(), fromDef, base))
+	}
+	if to.String() != base {
+		steps = append(steps, explainFromSI(si, base, toDef, to.String()))
+	}
+	steps = append(steps, result.String())
+	if footInch != nil && footInch.String() != result.String() {
+		steps = append(steps, footInch.String())
+	}
+	return strings.Join(steps, " = "), nil
+}
+
+// explainToSI is the step from a value to SI base units, e.g. 5 ft × 0.3048 m/ft
+func explainToSI(x float64, unit string, def siDef, base string) string {
+	step := fmt.Sprintf("%.6g %s", x, unit)
+	if def.Factor != 1 {
+		step += fmt.Sprintf(" × %.10g %s", def.Factor, per(base, unit))
+	}
+	if def.Offset != 0 {
+		step += fmt.Sprintf(" + %.10g %s", def.Offset, base)
+	}
+	return step
+}
+
+// explainFromSI is the step from SI base units to a unit, e.g. 1.524 m × 100 cm/m
+func explainFromSI(si float64, base string, def siDef, unit string) string {
+	step := fmt.Sprintf("%.6g %s", si, base)
+	if def.Offset != 0 {
+		step += fmt.Sprintf(" − %.10g %s", def.Offset, base)
+		if def.Factor != 1 {
+			step = "(" + step + ")"
+		}
+	}
+	switch {
+	case def.Factor == 1:
+	case def.Factor > 1:
+		step += fmt.Sprintf(" ÷ %.10g %s", def.Factor, per(base, unit))
+	default:
+		step += fmt.Sprintf(" × %.10g %s", 1/def.Factor, per(unit, base))
+	}
+	return step
+}
+
+// per writes a unit ratio, e.g. m/ft or (m/s)/mph
+func per(num, den string) string {
+	if strings.ContainsAny(num, "/·") {
+		num = "(" + num + ")"
+	}
+	if strings.ContainsAny(den, "/·") {
+		den = "(" + den + ")"
+	}
+	return num + "/" + den
+}
+
+func explainCurrency(from CurrencyVal, result UnitVal) (string, error) {
+	to := result.Unit().(*CurrencyUnit)
+	if to == from.U {
+		return fmt.Sprintf("%s = %s", from, result), nil
+	}
+	rate, exp, err := currencyRate(from.U, to, from.On)
+	if err != nil {
+		return "", ErrorCurrencyService
+	}
+	rate.Rate = shiftPow10(rate.Rate, exp)
+	if rate.Source == "exact" {
+		return fmt.Sprintf("%s × %.6g %s = %s", from, rate.Rate, per(to.id, from.U.id), result), nil
+	}
+	source := rate.Source
+	if rate.Base != "" && rate.Base != from.U.id {
+		source += " through " + rate.Base
+	}
+	return fmt.Sprintf("%s × %.6g %s = %s\nRate from %s at %s",
+		from, rate.Rate, per(to.id, from.U.id), result, source, rate.Time.UTC().Format(time.RFC1123)), nil
+}
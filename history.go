@@ -0,0 +1,286 @@
+package convert
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorNoHistoricalRates occurs when none of the rate providers have rates for past dates
+var ErrorNoHistoricalRates = errors.New("historical exchange rates are not available")
+
+// ErrorHistoryNotConfigured occurs when no historical rate providers were set. It is an ErrorNoHistoricalRates
+var ErrorHistoryNotConfigured error = historyNotConfigured{}
+
+type historyNotConfigured struct{}
+
+func (historyNotConfigured) Error() string {
+	return "Historical exchange rates aren't configured, only the latest rates can be used"
+}
+
+func (historyNotConfigured) Is(target error) bool {
+	return target == ErrorNoHistoricalRates
+}
+
+// HistoricalRateProvider is a RateProvider that also has the rates of past dates
+type HistoricalRateProvider interface {
+	RateProvider
+	// RatesOn gets the rates from a base currency on a date,
+	// or the last working day before it if none were published that day
+	RatesOn(date time.Time, base string, symbols []string) (Rates, error)
+}
+
+// RatesOn asks each of the providers with historical rates in turn until one of them answers
+func (c ChainProvider) RatesOn(date time.Time, base string, symbols []string) (Rates, error) {
+	errs := []error{ErrorNoHistoricalRates}
+	for _, provider := range c {
+		historical, ok := provider.(HistoricalRateProvider)
+		if !ok {
+			continue
+		}
+		rates, err := historical.RatesOn(date, base, symbols)
+		if err == nil {
+			return rates, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return Rates{}, errors.Join(errs...)
+}
+
+// ecbHistoryURL is the European Central Bank's archive of every day's reference rates since 1999
+const ecbHistoryURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.zip"
+
+// ecbHistoryMaxAge is how long the ECB history is kept in memory before it's read again for the days since
+const ecbHistoryMaxAge = 24 * time.Hour
+
+// ECBHistoryProvider has every day of the European Central Bank's euro reference rates,
+// from its eurofxref-hist CSV, either as the CSV or the zip it's published in.
+// The history is kept in memory for ecbHistoryMaxAge
+type ECBHistoryProvider struct {
+	// Path is a downloaded copy of the history, so it can be imported offline
+	Path string
+	// URL is where the history is downloaded from if there's no Path, ecbHistoryURL if empty
+	URL    string
+	Client *http.Client
+
+	lock   sync.Mutex
+	days   []ecbDay
+	loaded time.Time
+}
+
+// ecbDay is a day of rates from euros
+type ecbDay struct {
+	date  time.Time
+	rates map[string]float64
+}
+
+func (e *ECBHistoryProvider) Name() string {
+	if e.Path != "" {
+		return e.Path
+	}
+	u, err := url.Parse(e.url())
+	if err != nil {
+		return e.url()
+	}
+	return u.Host
+}
+
+func (e *ECBHistoryProvider) url() string {
+	if e.URL == "" {
+		return ecbHistoryURL
+	}
+	return e.URL
+}
+
+// history reads the history the first time it's needed and once it's older than ecbHistoryMaxAge, oldest day first
+func (e *ECBHistoryProvider) history() ([]ecbDay, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.days != nil && time.Since(e.loaded) < ecbHistoryMaxAge {
+		return e.days, nil
+	}
+
+	var data []byte
+	var err error
+	if e.Path != "" {
+		data, err = os.ReadFile(e.Path)
+	} else {
+		data, err = getBody(e.Client, e.url())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if days, err := parseECBHistory(data); err != nil {
+		return nil, err
+	} else {
+		e.days, e.loaded = days, time.Now()
+	}
+	return e.days, nil
+}
+
+// parseECBHistory reads the ECB history CSV, unzipping it first if needed:
+//
+//	Date,USD,JPY,...,
+//	2024-03-01,1.0830,162.50,...,
+func parseECBHistory(data []byte) ([]ecbDay, error) {
+	if bytes.HasPrefix(data, []byte("PK")) {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to unzip ECB history: %w", err)
+		}
+		if len(archive.File) == 0 {
+			return nil, fmt.Errorf("empty ECB history archive")
+		}
+		f, err := archive.File[0].Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to unzip ECB history: %w", err)
+		}
+		defer f.Close()
+		if data, err = io.ReadAll(f); err != nil {
+			return nil, fmt.Errorf("unable to unzip ECB history: %w", err)
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode ECB history: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("no rates in ECB history")
+	}
+
+	header := records[0]
+	var days []ecbDay
+	for _, record := range records[1:] {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode ECB history date: %w", err)
+		}
+		day := ecbDay{date, map[string]float64{}}
+		for i := 1; i < len(record) && i < len(header); i++ {
+			// Currencies that weren't traded that day are N/A
+			if rate, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64); err == nil {
+				day.rates[strings.TrimSpace(header[i])] = rate
+			}
+		}
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].date.Before(days[j].date) })
+	return days, nil
+}
+
+func (e *ECBHistoryProvider) Currencies() (map[string]string, error) {
+	days, err := e.history()
+	if err != nil {
+		return nil, err
+	}
+	currencies := map[string]string{"EUR": "EUR"}
+	for code := range days[len(days)-1].rates {
+		currencies[code] = code
+	}
+	return currencies, nil
+}
+
+// Rates gets the most recent rates in the history
+func (e *ECBHistoryProvider) Rates(base string, symbols []string) (Rates, error) {
+	return e.RatesOn(time.Now(), base, symbols)
+}
+
+func (e *ECBHistoryProvider) RatesOn(date time.Time, base string, symbols []string) (Rates, error) {
+	days, err := e.history()
+	if err != nil {
+		return Rates{}, err
+	}
+	// The last day on or before the date
+	i := sort.Search(len(days), func(i int) bool { return days[i].date.After(date) }) - 1
+	if i < 0 {
+		return Rates{}, fmt.Errorf("%w before %s", ErrorNoHistoricalRates, days[0].date.Format("2006-01-02"))
+	}
+	day := days[i]
+	return Rates{Base: "EUR", Rates: day.rates, Source: e.Name(), Time: day.date}.rebase(base, symbols)
+}
+
+// getRateOn gets the rate between two currencies on a date, or the latest rate if the date is zero.
+// Historical rates are kept for good once they're fetched
+func getRateOn(from, to *CurrencyUnit, on time.Time) (exchangeRate, error) {
+	if on.IsZero() {
+		return getRate(from, to)
+	}
+	if on.After(time.Now()) {
+		return exchangeRate{}, ErrorNoHistoricalRates
+	}
+
+	key := from.id + "_" + to.id + "@" + on.Format("2006-01-02")
+	if r, ok := storedHistoricalRate(key); ok {
+		return r, nil
+	}
+	if historyProvider == nil {
+		return exchangeRate{}, ErrorHistoryNotConfigured
+	}
+	rates, err := historyProvider.RatesOn(on, from.id, []string{to.id})
+	if err != nil {
+		return exchangeRate{}, err
+	}
+	rate, ok := rates.rate(to.id)
+	if !ok {
+		return exchangeRate{}, ErrorUnknownCurrency{to.id}
+	}
+	r := exchangeRate{Rate: rate, Source: rates.Source, Time: rates.Time}
+	storeHistoricalRate(key, r)
+	return r, nil
+}
+
+// asOf sets the date of the exchange rates a currency value is converted at
+func asOf(v UnitVal, on time.Time) UnitVal {
+	switch v := v.(type) {
+	case CurrencyVal:
+		v.On = on
+		return v
+	case UncertainVal:
+		v.Val = asOf(v.Val, on)
+		return v
+	default:
+		return v
+	}
+}
+
+// parseAsOf parses the date to convert currencies at, YYYY-MM-DD. Empty means the latest rates
+func parseAsOf(on string) (time.Time, error) {
+	if on == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(on))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date %s, expected YYYY-MM-DD", on)
+	}
+	if date.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("There are no exchange rates for %s yet", on)
+	}
+	return date, nil
+}
+
+// annotateAsOf notes which day's exchange rates a reply used, if it has any currencies
+func annotateAsOf(reply string, on time.Time, units ...UnitType) string {
+	if on.IsZero() {
+		return reply
+	}
+	for _, u := range units {
+		if _, ok := u.(*CurrencyUnit); ok {
+			return reply + "\n-# Exchange rates as of " + on.Format("2006-01-02")
+		}
+	}
+	return reply
+}
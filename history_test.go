@@ -0,0 +1,55 @@
+package convert
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHistory is a historical rate provider with the same rates on every date
+type fakeHistory struct {
+	fakeProvider
+}
+
+func (f fakeHistory) RatesOn(date time.Time, base string, symbols []string) (Rates, error) {
+	rates, err := f.Rates(base, symbols)
+	rates.Time = date
+	return rates, err
+}
+
+func TestHistoricalRates(t *testing.T) {
+	withCurrencies(t, map[string]string{"EUR": "Euro", "USD": "US Dollar"})
+	defer func() { historyProvider = nil }()
+
+	historyProvider = nil
+	checkReplies(t, Scope{}, []replyTest{
+		{"100 USD to EUR on 2019-06-01", ErrorHistoryNotConfigured.Error()},
+	})
+
+	SetHistoricalRateProviders(fakeHistory{fakeProvider{name: "Fake", rates: Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.25}}}})
+	checkReplies(t, Scope{}, []replyTest{
+		{"100 USD to EUR on 2019-06-02", "100.00 USD = 80.00 EUR\n-# Exchange rates as of 2019-06-02"},
+	})
+
+	// A provider that answers without the currency has no rate for it, rather than a rate of 0
+	historyProvider = emptyHistory{}
+	usd, _ := LookupUnit("USD")
+	eur, _ := LookupUnit("EUR")
+	on := time.Date(2019, 6, 3, 0, 0, 0, 0, time.UTC)
+	if _, err := getRateOn(usd.(*CurrencyUnit), eur.(*CurrencyUnit), on); !errors.As(err, &ErrorUnknownCurrency{}) {
+		t.Errorf("getRateOn() error = %v, want ErrorUnknownCurrency", err)
+	}
+	if reply := ProcessIn(Scope{}, "100 USD to EUR on 2019-06-03"); strings.Contains(reply, "as of") {
+		t.Errorf("ProcessIn() = %q, want no exchange rate date on a failure", reply)
+	}
+}
+
+// emptyHistory is a historical rate provider that answers without any rates
+type emptyHistory struct {
+	emptyProvider
+}
+
+func (emptyHistory) RatesOn(date time.Time, base string, symbols []string) (Rates, error) {
+	return Rates{Base: base, Rates: map[string]float64{}, Time: date}, nil
+}
@@ -0,0 +1,208 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	p "unit-bot/parser"
+)
+
+// humanParts are the units a duration is broken into for people to read, largest first
+var humanParts = []struct {
+	unit           *DurationUnit
+	singular, name string
+}{
+	{Year, "year", "years"},
+	{Month, "month", "months"},
+	{Week, "week", "weeks"},
+	{Day, "day", "days"},
+	{Hour, "hr", "hr"},
+	{Minute, "min", "min"},
+	{Second, "s", "s"},
+}
+
+// seconds finds the length of a duration in seconds
+func seconds(v UnitVal) (float64, error) {
+	if v.Unit().Dimension() != UnitDimensionDuration {
+		return 0, fmt.Errorf("Only durations can be written that way, not %s", v.Unit())
+	}
+	secs, err := v.Convert(Second)
+	if err != nil {
+		return 0, err
+	}
+	return magnitude(secs), nil
+}
+
+// unitSeconds is how many seconds there are in a duration unit
+func unitSeconds(u *DurationUnit) float64 {
+	return float64(u.fromFloat(1))
+}
+
+// Human breaks a duration into years, months, weeks, days, hours, minutes and seconds,
+// e.g. 100000 s is 1 day 3 hr 46 min 40 s
+func Human(v UnitVal) (string, error) {
+	return HumanDepth(v, "")
+}
+
+// HumanDepth breaks a duration into at most depth parts, rounding the last,
+// e.g. human:2 writes 100000 s as 1 day 4 hr
+func HumanDepth(v UnitVal, depth string) (string, error) {
+	secs, err := seconds(v)
+	if err != nil {
+		return "", err
+	}
+	parts := len(humanParts)
+	if depth != "" {
+		if parts, err = strconv.Atoi(depth); err != nil || parts < 1 {
+			return "", fmt.Errorf("Invalid depth %s, it should be a number of parts like human:2", depth)
+		}
+	}
+	return fmt.Sprintf("%s = %s", v, humanize(secs, parts)), nil
+}
+
+// humanize writes a number of seconds as at most n parts
+func humanize(secs float64, n int) string {
+	sign := ""
+	if secs < 0 {
+		sign, secs = "-", -secs
+	}
+
+	// Round to the smallest part shown, which can carry into a larger part
+	first := len(humanParts) - 1
+	for i, part := range humanParts {
+		if secs >= unitSeconds(part.unit) {
+			first = i
+			break
+		}
+	}
+	if last := first + n - 1; last < len(humanParts)-1 {
+		size := unitSeconds(humanParts[last].unit)
+		secs = math.Round(secs/size) * size
+	}
+
+	var words []string
+	for i, part := range humanParts {
+		if len(words) == n {
+			break
+		}
+		size := unitSeconds(part.unit)
+		count := math.Floor(secs/size + 1e-9)
+		if i == len(humanParts)-1 {
+			count = math.Round(secs*1000) / 1000
+		}
+		if count <= 0 {
+			if len(words) > 0 {
+				// Parts after the first count towards the depth even when they're zero
+				n--
+			}
+			continue
+		}
+		secs = math.Max(secs-count*size, 0)
+
+		name := part.name
+		if count == 1 {
+			name = part.singular
+		}
+		words = append(words, fmt.Sprintf("%g %s", count, name))
+	}
+	if len(words) == 0 {
+		return "0 s"
+	}
+	return sign + strings.Join(words, " ")
+}
+
+// ISODuration writes a duration in ISO-8601, e.g. P1DT3H46M40S
+func ISODuration(v UnitVal) (string, error) {
+	secs, err := seconds(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s = %s", v, formatISODuration(secs)), nil
+}
+
+// isoParts are the parts of an ISO-8601 duration, with whether they come after the T
+var isoParts = []struct {
+	unit   *DurationUnit
+	letter string
+	time   bool
+}{
+	{Year, "Y", false},
+	{Month, "M", false},
+	{Day, "D", false},
+	{Hour, "H", true},
+	{Minute, "M", true},
+	{Second, "S", true},
+}
+
+func formatISODuration(secs float64) string {
+	var date, clock strings.Builder
+	if secs < 0 {
+		date.WriteString("-")
+		secs = -secs
+	}
+	date.WriteString("P")
+	for i, part := range isoParts {
+		size := unitSeconds(part.unit)
+		count := math.Floor(secs/size + 1e-9)
+		if i == len(isoParts)-1 {
+			count = math.Round(secs*1000) / 1000
+		}
+		if count <= 0 {
+			continue
+		}
+		secs = math.Max(secs-count*size, 0)
+		if part.time {
+			fmt.Fprintf(&clock, "%g%s", count, part.letter)
+		} else {
+			fmt.Fprintf(&date, "%g%s", count, part.letter)
+		}
+	}
+	if clock.Len() > 0 {
+		return date.String() + "T" + clock.String()
+	}
+	if date.Len() == len("P") || date.Len() == len("-P") {
+		return "PT0S"
+	}
+	return date.String()
+}
+
+var (
+	isoDurationPattern = regexp.MustCompile(`^(-)?P(?:([\d.]+)Y)?(?:([\d.]+)M)?(?:([\d.]+)W)?(?:([\d.]+)D)?(?:T(?:([\d.]+)H)?(?:([\d.]+)M)?(?:([\d.]+)S)?)?$`)
+	// isoDurationUnits are the units of the parts of isoDurationPattern
+	isoDurationUnits = []*DurationUnit{Year, Month, Week, Day, Hour, Minute, Second}
+	isoDurationVal   = p.Map(p.Token(`-?P(?:[\d.]+[YMWD])*(?:T(?:[\d.]+[HMS])+)?\b`), mapISODuration).Filter(func(v any) bool { return v != nil })
+)
+
+// ParseISODuration parses an ISO-8601 duration, e.g. P1DT3H46M40S, into seconds
+func ParseISODuration(s string) (float64, error) {
+	m := isoDurationPattern.FindStringSubmatch(s)
+	if m == nil || strings.HasSuffix(s, "P") || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("Invalid ISO-8601 duration %s", s)
+	}
+	secs := 0.0
+	for i, u := range isoDurationUnits {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(m[i+2], 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid ISO-8601 duration %s", s)
+		}
+		secs += n * unitSeconds(u)
+	}
+	if m[1] == "-" {
+		secs = -secs
+	}
+	return secs, nil
+}
+
+func mapISODuration(s string) any {
+	secs, err := ParseISODuration(s)
+	if err != nil {
+		return nil
+	}
+	return Second.FromFloat(secs)
+}
@@ -0,0 +1,22 @@
+package convert
+
+import "testing"
+
+func TestHumanDurations(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"3600 s to human", "3600 s = 1 hr"},
+		{"90061 s to human", "90061 s = 1 day 1 hr 1 min 1 s"},
+		{"100000 s to human:2", "100000 s = 1 day 4 hr"},
+		{"5 kg to human", "Only durations can be written that way, not kg"},
+	})
+}
+
+func TestISODurations(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"PT1H30M to min", "5400 s = 90 min"},
+		{"P1DT2H to hr", "93600 s = 26 hr"},
+		{"90 min to isoduration", "90 min = PT1H30M"},
+		{"1.5 days to isoduration", "1.5 days = P1DT12H"},
+		{"P1X to min", "Usage: !conv [amount][from-unit] to [to-unit]"},
+	})
+}
@@ -0,0 +1,334 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	p "unit-bot/parser"
+)
+
+// CPISeries is a country's consumer price index by year, the annual average
+type CPISeries struct {
+	Name   string          `json:"name"`
+	Source string          `json:"source"`
+	Index  map[int]float64 `json:"index"`
+}
+
+// latest is the last year in the series
+func (s *CPISeries) latest() int {
+	year := 0
+	for y := range s.Index {
+		if y > year {
+			year = y
+		}
+	}
+	return year
+}
+
+// at is the index in a year
+func (s *CPISeries) at(currency string, year int) (float64, error) {
+	index, ok := s.Index[year]
+	if !ok {
+		return 0, fmt.Errorf("No %s price index for %d, %s", currency, year, s.coverage())
+	}
+	return index, nil
+}
+
+// coverage describes the years the series covers
+func (s *CPISeries) coverage() string {
+	first := s.latest()
+	for y := range s.Index {
+		if y < first {
+			first = y
+		}
+	}
+	return fmt.Sprintf("it covers %d to %d", first, s.latest())
+}
+
+var (
+	cpiLock sync.RWMutex
+	// cpiSeries are the price indices by the currency they're in, updated by LoadCPI
+	cpiSeries = map[string]*CPISeries{
+		"USD": {
+			Name:   "US CPI-U",
+			Source: "Bureau of Labor Statistics, 1982-84=100",
+			Index: map[int]float64{
+				1950: 24.1, 1951: 26.0, 1952: 26.5, 1953: 26.7, 1954: 26.9,
+				1955: 26.8, 1956: 27.2, 1957: 28.1, 1958: 28.9, 1959: 29.1,
+				1960: 29.6, 1961: 29.9, 1962: 30.2, 1963: 30.6, 1964: 31.0,
+				1965: 31.5, 1966: 32.4, 1967: 33.4, 1968: 34.8, 1969: 36.7,
+				1970: 38.8, 1971: 40.5, 1972: 41.8, 1973: 44.4, 1974: 49.3,
+				1975: 53.8, 1976: 56.9, 1977: 60.6, 1978: 65.2, 1979: 72.6,
+				1980: 82.4, 1981: 90.9, 1982: 96.5, 1983: 99.6, 1984: 103.9,
+				1985: 107.6, 1986: 109.6, 1987: 113.6, 1988: 118.3, 1989: 124.0,
+				1990: 130.7, 1991: 136.2, 1992: 140.3, 1993: 144.5, 1994: 148.2,
+				1995: 152.4, 1996: 156.9, 1997: 160.5, 1998: 163.0, 1999: 166.6,
+				2000: 172.2, 2001: 177.1, 2002: 179.9, 2003: 184.0, 2004: 188.9,
+				2005: 195.3, 2006: 201.6, 2007: 207.342, 2008: 215.303, 2009: 214.537,
+				2010: 218.056, 2011: 224.939, 2012: 229.594, 2013: 232.957, 2014: 236.736,
+				2015: 237.017, 2016: 240.007, 2017: 245.120, 2018: 251.107, 2019: 255.657,
+				2020: 258.811, 2021: 270.970, 2022: 292.655, 2023: 304.702, 2024: 313.689,
+			},
+		},
+		"EUR": {
+			Name:   "euro area HICP",
+			Source: "Eurostat, 2015=100",
+			Index: map[int]float64{
+				1996: 71.4, 1997: 72.6, 1998: 73.5, 1999: 74.3, 2000: 75.9,
+				2001: 77.7, 2002: 79.5, 2003: 81.2, 2004: 83.0, 2005: 84.8,
+				2006: 86.6, 2007: 88.5, 2008: 91.4, 2009: 91.7, 2010: 93.1,
+				2011: 95.6, 2012: 98.0, 2013: 99.4, 2014: 99.8, 2015: 100.0,
+				2016: 100.2, 2017: 101.7, 2018: 103.5, 2019: 104.8, 2020: 105.1,
+				2021: 107.8, 2022: 116.9, 2023: 123.2, 2024: 126.1,
+			},
+		},
+		"GBP": {
+			Name:   "UK CPI",
+			Source: "Office for National Statistics, 2015=100",
+			Index: map[int]float64{
+				1988: 49.6, 1989: 52.1, 1990: 55.8, 1991: 60.0, 1992: 62.6,
+				1993: 64.1, 1994: 65.4, 1995: 67.1, 1996: 68.8, 1997: 70.0,
+				1998: 71.1, 1999: 72.1, 2000: 72.6, 2001: 73.5, 2002: 74.5,
+				2003: 75.5, 2004: 76.5, 2005: 78.1, 2006: 79.9, 2007: 81.7,
+				2008: 84.7, 2009: 86.5, 2010: 89.4, 2011: 93.4, 2012: 96.0,
+				2013: 98.5, 2014: 100.0, 2015: 100.0, 2016: 100.7, 2017: 103.4,
+				2018: 106.0, 2019: 107.9, 2020: 108.9, 2021: 111.7, 2022: 121.9,
+				2023: 130.8, 2024: 134.0,
+			},
+		},
+		"CAD": {
+			Name:   "Canada CPI",
+			Source: "Statistics Canada, 2002=100",
+			Index: map[int]float64{
+				1989: 74.8, 1990: 78.4, 1991: 82.8, 1992: 84.0, 1993: 85.5,
+				1994: 85.7, 1995: 87.6, 1996: 89.0, 1997: 90.4, 1998: 91.3,
+				1999: 92.9, 2000: 95.4, 2001: 97.8, 2002: 100.0, 2003: 102.8,
+				2004: 104.7, 2005: 107.0, 2006: 109.1, 2007: 111.4, 2008: 114.1,
+				2009: 114.4, 2010: 116.5, 2011: 119.8, 2012: 121.6, 2013: 122.7,
+				2014: 125.1, 2015: 126.4, 2016: 128.2, 2017: 130.3, 2018: 133.3,
+				2019: 135.8, 2020: 136.7, 2021: 141.4, 2022: 151.0, 2023: 156.9,
+				2024: 160.7,
+			},
+		},
+		"AUD": {
+			Name:   "Australia CPI",
+			Source: "Australian Bureau of Statistics, 2012=100",
+			Index: map[int]float64{
+				1989: 52.9, 1990: 56.8, 1991: 58.6, 1992: 59.2, 1993: 60.2,
+				1994: 61.4, 1995: 64.2, 1996: 65.9, 1997: 66.1, 1998: 66.7,
+				1999: 67.7, 2000: 70.7, 2001: 73.8, 2002: 76.0, 2003: 78.2,
+				2004: 80.0, 2005: 82.1, 2006: 85.0, 2007: 87.0, 2008: 90.8,
+				2009: 92.4, 2010: 95.1, 2011: 98.2, 2012: 100.0, 2013: 102.4,
+				2014: 105.0, 2015: 106.5, 2016: 107.9, 2017: 110.1, 2018: 112.2,
+				2019: 114.0, 2020: 115.0, 2021: 118.2, 2022: 126.0, 2023: 133.1,
+				2024: 137.3,
+			},
+		},
+		"JPY": {
+			Name:   "Japan CPI",
+			Source: "Statistics Bureau of Japan, 2020=100",
+			Index: map[int]float64{
+				1989: 86.8, 1990: 89.5, 1991: 92.4, 1992: 94.0, 1993: 95.2,
+				1994: 95.9, 1995: 95.8, 1996: 95.9, 1997: 97.6, 1998: 98.2,
+				1999: 97.9, 2000: 97.2, 2001: 96.5, 2002: 95.7, 2003: 95.4,
+				2004: 95.4, 2005: 95.1, 2006: 95.4, 2007: 95.4, 2008: 96.7,
+				2009: 95.4, 2010: 94.7, 2011: 94.4, 2012: 94.4, 2013: 94.8,
+				2014: 97.3, 2015: 98.1, 2016: 98.0, 2017: 98.5, 2018: 99.5,
+				2019: 100.0, 2020: 100.0, 2021: 99.8, 2022: 102.3, 2023: 105.6,
+				2024: 108.4,
+			},
+		},
+	}
+)
+
+// LoadCPI loads price indices from a JSON file of series by currency, e.g.
+//
+//	{"GBP": {"name": "UK CPI", "source": "ONS, 2015=100", "index": {"2023": 130.5}}}
+//
+// Years are added to the bundled series, replacing any they already have
+func LoadCPI(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var loaded map[string]*CPISeries
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("unable to decode price indices: %w", err)
+	}
+
+	cpiLock.Lock()
+	defer cpiLock.Unlock()
+	for currency, series := range loaded {
+		currency = strings.ToUpper(currency)
+		existing, ok := cpiSeries[currency]
+		if !ok {
+			cpiSeries[currency] = series
+			continue
+		}
+		// Series that have been looked up may still be in use, so a new one replaces it
+		merged := &CPISeries{Name: existing.Name, Source: existing.Source, Index: make(map[int]float64, len(existing.Index))}
+		if series.Name != "" {
+			merged.Name = series.Name
+		}
+		if series.Source != "" {
+			merged.Source = series.Source
+		}
+		for year, index := range existing.Index {
+			merged.Index[year] = index
+		}
+		for year, index := range series.Index {
+			merged.Index[year] = index
+		}
+		cpiSeries[currency] = merged
+	}
+	slog.Info("Loaded price indices", "path", path, "currencies", len(loaded))
+	return nil
+}
+
+func lookupCPI(currency string) (*CPISeries, bool) {
+	cpiLock.RLock()
+	defer cpiLock.RUnlock()
+	series, ok := cpiSeries[currency]
+	return series, ok && len(series.Index) > 0
+}
+
+// inflationCommand adjusts an amount of money for inflation between years,
+// e.g. 50 USD@1985 to USD@2024, or to another currency
+type inflationCommand struct {
+	from     unparsedUnitVal
+	fromYear int
+	to       string
+	// toYear is the latest year there's a price index for if it's 0
+	toYear int
+}
+
+var (
+	atYear       = p.Map(p.Token(`@\d{4}`), func(s string) int { year, _ := strconv.Atoi(s[1:]); return year })
+	pricedAmount = p.First(
+		p.Parse2(p.Float, unitToken, mapSimpleUnit),
+		p.Parse2(currencyPrefix, p.Float, mapCurrency),
+	)
+	inflationExpr = p.Parse3(
+		p.Parse2(pricedAmount, atYear, func(v any, year int) inflationCommand {
+			return inflationCommand{from: v.(unparsedUnitVal), fromYear: year}
+		}),
+		p.Atom(`to`),
+		p.Parse2(unitToken, atYear.Or(0), func(to string, year int) inflationCommand { return inflationCommand{to: to, toYear: year} }),
+		func(from inflationCommand, _ string, to inflationCommand) inflationCommand {
+			from.to, from.toYear = to.to, to.toYear
+			return from
+		},
+	)
+)
+
+// parseInflation parses a whole inflation adjustment, so the slash command can use the same syntax
+func parseInflation(expr string) (inflationCommand, bool) {
+	cmd, n, ok := inflationExpr([]byte(expr))
+	return cmd, ok && strings.TrimSpace(expr[n:]) == ""
+}
+
+func (cmd inflationCommand) run(scope Scope) string {
+	from, err := resolveValue(scope, cmd.from)
+	if err != nil {
+		return err.Error()
+	}
+	fromMoney, ok := from.(CurrencyVal)
+	if !ok {
+		return fmt.Sprintf("Only money can be adjusted for inflation, not %s", from.Unit())
+	}
+	toUnit, ok := lookupUnitIn(scope, cmd.to)
+	if !ok {
+		return ErrorInvalidUnit{cmd.to}.Error()
+	}
+	to, ok := toUnit.(*CurrencyUnit)
+	if !ok {
+		return fmt.Sprintf("Only money can be adjusted for inflation, not %s", toUnit)
+	}
+
+	// Inflation is measured in the currency it's from if there's an index for it,
+	// otherwise the amount is exchanged at the time and measured in the currency it's going to
+	series, ok := lookupCPI(fromMoney.U.id)
+	inflateIn := fromMoney.U
+	if !ok {
+		if series, ok = lookupCPI(to.id); !ok {
+			return fmt.Sprintf("No price index for %s or %s", fromMoney.U.id, to.id)
+		}
+		inflateIn = to
+	}
+	toYear := cmd.toYear
+	if toYear == 0 {
+		toYear = series.latest()
+	}
+
+	amount := UnitVal(fromMoney)
+	var ratesOn []time.Time
+	if inflateIn != fromMoney.U {
+		on := ratesOf(cmd.fromYear)
+		if amount, err = asOf(amount, on).Convert(inflateIn); err != nil {
+			if errors.Is(err, ErrorNoHistoricalRates) {
+				return fmt.Sprintf("No price index for %s, and no exchange rates from %d to adjust it in %s", fromMoney.U.id, cmd.fromYear, inflateIn.id)
+			}
+			return err.Error()
+		}
+		ratesOn = append(ratesOn, on)
+	}
+
+	fromIndex, err := series.at(inflateIn.id, cmd.fromYear)
+	if err != nil {
+		return err.Error()
+	}
+	toIndex, err := series.at(inflateIn.id, toYear)
+	if err != nil {
+		return err.Error()
+	}
+	result := CurrencyVal{V: amount.(CurrencyVal).V * toIndex / fromIndex, U: inflateIn}
+
+	var converted UnitVal = result
+	var latestRates bool
+	if inflateIn != to {
+		// Exchanged at that year's rates, or today's if it's the latest.
+		// Years before the rate history starts, e.g. before the euro in 1999, are exchanged at today's rates too
+		var on time.Time
+		if cmd.toYear != 0 {
+			on = ratesOf(cmd.toYear)
+		}
+		converted, err = asOf(result, on).Convert(to)
+		if errors.Is(err, ErrorNoHistoricalRates) && !on.IsZero() {
+			on, latestRates = time.Time{}, true
+			converted, err = result.Convert(to)
+		}
+		if err != nil {
+			return err.Error()
+		}
+		if !on.IsZero() {
+			ratesOn = append(ratesOn, on)
+		}
+	}
+
+	reply := fmt.Sprintf("%s in %d = %s in %d\n-# Adjusted for inflation with the %s (%s)",
+		fromMoney, cmd.fromYear, converted, toYear, series.Name, series.Source)
+	for _, on := range ratesOn {
+		reply = annotateAsOf(reply, on, to)
+	}
+	if latestRates {
+		reply += fmt.Sprintf("\n-# There are no exchange rates from %d, so it was exchanged at the latest rates", cmd.toYear)
+	}
+	return reply
+}
+
+// ratesOf is the day a year's exchange rates are taken from, the middle of it to match its average prices.
+// It's zero, the latest rates, if that hasn't happened yet
+func ratesOf(year int) time.Time {
+	mid := time.Date(year, time.July, 1, 0, 0, 0, 0, time.UTC)
+	if mid.After(time.Now()) {
+		return time.Time{}
+	}
+	return mid
+}
@@ -0,0 +1,37 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCPI(t *testing.T) {
+	cpiLock.Lock()
+	saved := cpiSeries["USD"]
+	cpiLock.Unlock()
+	defer func() {
+		cpiLock.Lock()
+		cpiSeries["USD"] = saved
+		cpiLock.Unlock()
+	}()
+
+	before, _ := lookupCPI("USD")
+	index2000 := before.Index[2000]
+	path := filepath.Join(t.TempDir(), "cpi.json")
+	if err := os.WriteFile(path, []byte(`{"usd": {"source": "Test", "index": {"2000": 1, "2100": 999}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadCPI(path); err != nil {
+		t.Fatalf("LoadCPI() error = %v", err)
+	}
+
+	after, _ := lookupCPI("USD")
+	if after.Index[2000] != 1 || after.Index[2100] != 999 || after.Source != "Test" || after.Name != before.Name {
+		t.Errorf("LoadCPI() series = %+v, want the loaded years and source added", after)
+	}
+	// A series that was already looked up isn't changed underneath whoever has it
+	if before.Index[2000] != index2000 || before.Index[2100] != 0 {
+		t.Errorf("LoadCPI() changed the series it replaced: %+v", before)
+	}
+}
@@ -12,7 +12,11 @@ type LengthUnit struct {
 	SimpleUnit[unit.Length]
 }
 
-type LengthVal struct{ SimpleUnitValue[unit.Length] }
+// LengthVal is a length value with unit
+type LengthVal struct {
+	SimpleUnitValue[unit.Length]
+	lengthUnit *LengthUnit
+}
 
 // Length units
 var (
@@ -34,7 +38,7 @@ var (
 )
 
 func (u *LengthUnit) FromFloat(f float64) UnitVal {
-	return LengthVal{u.SimpleUnit.FromFloat(f).(SimpleUnitValue[unit.Length])}
+	return LengthVal{u.SimpleUnit.FromFloat(f).(SimpleUnitValue[unit.Length]), u}
 }
 
 // Convert implements UnitVal conversion
@@ -42,16 +46,50 @@ func (lv LengthVal) Convert(to UnitType) (UnitVal, error) {
 	switch to := to.(type) {
 	case *LengthUnit:
 		lv.unit = &to.SimpleUnit
+		lv.lengthUnit = to
 		return lv, nil
 	case *FootInchUnit:
 		feet, fraction := math.Modf(lv.value.Feet())
 		inches := (unit.Length(fraction) * unit.Foot).Inches()
 		return FootInchVal{feet, inches}, nil
 	default:
-		return nil, ErrorConversion{lv.unit, to}
+		return convertSI(lv, to)
 	}
 }
 
+// Unit implements UnitVal
+func (lv LengthVal) Unit() UnitType {
+	return lv.lengthUnit
+}
+
+func (lv LengthVal) Add(other UnitVal) (UnitVal, error) {
+	return combine("add", lv, other, 1)
+}
+
+func (lv LengthVal) Sub(other UnitVal) (UnitVal, error) {
+	return combine("subtract", lv, other, -1)
+}
+
+func (lv LengthVal) Mul(k float64) (UnitVal, error) {
+	return scale(lv, k), nil
+}
+
+func (lv LengthVal) Div(k float64) (UnitVal, error) {
+	return divide(lv, k)
+}
+
+func (lv LengthVal) Neg() (UnitVal, error) {
+	return scale(lv, -1), nil
+}
+
+func (lv LengthVal) Abs() (UnitVal, error) {
+	return scale(lv, math.Copysign(1, lv.float())), nil
+}
+
+func (lv LengthVal) Cmp(other UnitVal) (int, error) {
+	return compare(lv, other)
+}
+
 // FootInchUnit is a unit of both feet + inches
 type FootInchUnit struct{}
 
@@ -69,6 +107,10 @@ func (FootInchUnit) String() string {
 	return "feet + inches"
 }
 
+func (FootInchUnit) si() (siDef, bool) {
+	return siDef{Factor: float64(unit.Foot), Dims: dimensionDims[UnitDimensionLength]}, true
+}
+
 func (FootInchUnit) Dimension() UnitDimension {
 	return UnitDimensionLength
 }
@@ -88,10 +130,56 @@ func (val FootInchVal) Convert(to UnitType) (UnitVal, error) {
 	case *LengthUnit:
 		feet := unit.Length(val.Feet) * unit.Foot
 		inches := unit.Length(val.Inches) * unit.Inch
-		return LengthVal{SimpleUnitValue[unit.Length]{feet + inches, &to.SimpleUnit}}, nil
+		return LengthVal{SimpleUnitValue[unit.Length]{feet + inches, &to.SimpleUnit}, to}, nil
 	default:
-		return nil, ErrorConversion{FootInch, to}
+		return convertSI(val, to)
+	}
+}
+
+// feet converts the value to a plain length, which arithmetic is done in
+func (val FootInchVal) feet() UnitVal {
+	feet, _ := val.Convert(Foot)
+	return feet
+}
+
+// footInch converts the result of arithmetic back to feet + inches
+func footInch(v UnitVal, err error) (UnitVal, error) {
+	if err != nil {
+		return nil, err
 	}
+	return v.Convert(FootInch)
+}
+
+func (val FootInchVal) Add(other UnitVal) (UnitVal, error) {
+	return footInch(combine("add", val.feet(), other, 1))
+}
+
+func (val FootInchVal) Sub(other UnitVal) (UnitVal, error) {
+	return footInch(combine("subtract", val.feet(), other, -1))
+}
+
+func (val FootInchVal) Mul(k float64) (UnitVal, error) {
+	return footInch(scale(val.feet(), k), nil)
+}
+
+func (val FootInchVal) Div(k float64) (UnitVal, error) {
+	return footInch(divide(val.feet(), k))
+}
+
+func (val FootInchVal) Neg() (UnitVal, error) {
+	return FootInchVal{-val.Feet, -val.Inches}, nil
+}
+
+func (val FootInchVal) Abs() (UnitVal, error) {
+	return FootInchVal{math.Abs(val.Feet), math.Abs(val.Inches)}, nil
+}
+
+func (val FootInchVal) Cmp(other UnitVal) (int, error) {
+	return compare(val.feet(), other)
+}
+
+func (val FootInchVal) float() float64 {
+	return val.Feet + val.Inches/12
 }
 
 func (FootInchVal) Unit() UnitType {
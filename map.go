@@ -51,6 +51,28 @@ var supportedUnits = map[UnitType][]string{
 	Hour:   {"hr", "hrs", "hour", "hours"},
 	Day:    {"day", "days"},
 	Week:   {"wk", "week", "weeks"},
-	Month:  {"month", "months"},
-	Year:   {"yr", "year", "years"},
+	Month:  {"month", "months", "calendarmonth", "calendarmonths"},
+	Year:   {"yr", "year", "years", "gregorianyear", "gregorianyears"},
+
+	ThirtyDayMonth: {"month30", "months30", "thirtydaymonth", "thirtydaymonths"},
+	JulianYear:     {"julianyear", "julianyears"},
+	SiderealYear:   {"siderealyear", "siderealyears"},
+	TropicalYear:   {"tropicalyear", "tropicalyears", "solaryear", "solaryears"},
+
+	// Date formats
+	Unix:      {"unix", "epoch", "posix"},
+	UnixMilli: {"unixms", "unix_ms", "epochms"},
+	ISO8601:   {"iso", "iso8601", "iso-8601"},
+	RFC1123:   {"rfc1123", "rfc-1123", "http-date"},
+	DateTime:  {"date", "datetime"},
+
+	// Derived
+	Mole:    {"mol", "mole", "moles"},
+	Newton:  {"N", "newton", "newtons"},
+	Joule:   {"J", "joule", "joules"},
+	Watt:    {"W", "watt", "watts"},
+	Pascal:  {"Pa", "pascal", "pascals"},
+	Hertz:   {"Hz", "hertz"},
+	Ampere:  {"A", "amp", "amps", "ampere", "amperes"},
+	Candela: {"cd", "candela", "candelas"},
 }
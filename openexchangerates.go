@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// openExchangeRatesURL is the API of openexchangerates.org
+const openExchangeRatesURL = "https://openexchangerates.org/api"
+
+// OpenExchangeRatesProvider gets rates from an openexchangerates.org style JSON API,
+// with latest.json for rates and currencies.json for names
+type OpenExchangeRatesProvider struct {
+	// URL is the base of the API, openExchangeRatesURL if empty
+	URL    string
+	AppID  string
+	Client *http.Client
+}
+
+type openExchangeRatesLatest struct {
+	Timestamp int64              `json:"timestamp"`
+	Base      string             `json:"base"`
+	Rates     map[string]float64 `json:"rates"`
+}
+
+func (o OpenExchangeRatesProvider) Name() string {
+	u, err := url.Parse(o.baseURL())
+	if err != nil {
+		return o.baseURL()
+	}
+	return u.Host
+}
+
+func (o OpenExchangeRatesProvider) baseURL() string {
+	if o.URL == "" {
+		return openExchangeRatesURL
+	}
+	return o.URL
+}
+
+// endpoint is the URL of an API endpoint, with the app ID
+func (o OpenExchangeRatesProvider) endpoint(path string) string {
+	u, _ := url.Parse(o.baseURL() + "/" + path)
+	q := u.Query()
+	if o.AppID != "" {
+		q.Set("app_id", o.AppID)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (o OpenExchangeRatesProvider) Currencies() (map[string]string, error) {
+	body, err := getBody(o.Client, o.endpoint("currencies.json"))
+	if err != nil {
+		return nil, err
+	}
+	var currencies map[string]string
+	if err := json.Unmarshal(body, &currencies); err != nil {
+		return nil, fmt.Errorf("unable to decode currencies response: %w\nBody: %v", err, string(body))
+	}
+	return currencies, nil
+}
+
+// Rates gets the latest rates, which are from USD on the free plan, and rebases them
+func (o OpenExchangeRatesProvider) Rates(base string, symbols []string) (Rates, error) {
+	body, err := getBody(o.Client, o.endpoint("latest.json"))
+	if err != nil {
+		return Rates{}, err
+	}
+	var latest openExchangeRatesLatest
+	if err := json.Unmarshal(body, &latest); err != nil {
+		return Rates{}, fmt.Errorf("unable to decode rates response: %w\nBody: %v", err, string(body))
+	}
+	if latest.Base == "" {
+		latest.Base = "USD"
+	}
+
+	rates := Rates{Base: latest.Base, Rates: latest.Rates, Source: o.Name(), Time: time.Unix(latest.Timestamp, 0).UTC()}
+	return rates.rebase(base, symbols)
+}
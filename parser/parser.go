@@ -140,6 +140,50 @@ func First[T any](ps ...Parser[T]) Parser[T] {
 	}
 }
 
+// SepBy matches one or more of p separated by sep
+// Will result in a slice of all of the values parsed by p
+func SepBy[T, S any](p Parser[T], sep Parser[S]) Parser[[]T] {
+	return func(s []byte) ([]T, int, bool) {
+		v, n, ok := p(s)
+		if !ok {
+			return nil, 0, false
+		}
+		vs := []T{v}
+		for {
+			_, m, ok := sep(s[n:])
+			if !ok {
+				break
+			}
+			v, k, ok := p(s[n+m:])
+			if !ok {
+				break
+			}
+			vs = append(vs, v)
+			n += m + k
+		}
+		return vs, n, true
+	}
+}
+
+// Many matches p zero or more times
+// Will result in a slice of all of the parsed values
+func Many[T any](p Parser[T]) Parser[[]T] {
+	return func(s []byte) ([]T, int, bool) {
+		var (
+			vs  []T
+			sum int
+		)
+		for {
+			v, n, ok := p(s[sum:])
+			if !ok || n == 0 {
+				return vs, sum, true
+			}
+			vs = append(vs, v)
+			sum += n
+		}
+	}
+}
+
 // Atom scans for a single atom, skipping whitespace
 func Atom(val string) Parser[string] {
 	b := []byte(val)
@@ -198,6 +242,18 @@ func (p Parser[T]) Or(or T) Parser[T] {
 	}
 }
 
+// Filter fails the parser if its result doesn't satisfy the predicate
+func (p Parser[T]) Filter(f func(T) bool) Parser[T] {
+	return func(s []byte) (T, int, bool) {
+		res, n, ok := p(s)
+		if ok && f(res) {
+			return res, n, true
+		}
+		var t T
+		return t, 0, false
+	}
+}
+
 // Map maps the result of a parser to a different result
 // If the Mapper returns nil, the parser returns as invalid
 func Map[A, B any](p Parser[A], f func(A) B) Parser[B] {
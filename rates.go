@@ -0,0 +1,227 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// RateProvider is a source of exchange rates
+type RateProvider interface {
+	// Name says where the rates come from, e.g. ecb.europa.eu
+	Name() string
+	// Currencies lists the codes of the currencies it has rates for, with their names
+	Currencies() (map[string]string, error)
+	// Rates gets the rates from a base currency to each of the symbols,
+	// or to every currency it has if symbols is nil. Providers that can't list every rate at once
+	// return ErrorNoRateTable for nil symbols
+	Rates(base string, symbols []string) (Rates, error)
+}
+
+var (
+	// ErrorNoRateTable occurs when a provider can only get rates to the currencies asked for
+	ErrorNoRateTable = errors.New("rates to every currency are not available")
+	// ErrorNoRates occurs when a provider answers without any rates
+	ErrorNoRates = errors.New("no rates in response")
+)
+
+// Rates are exchange rates from a base currency, with where and when they were published
+type Rates struct {
+	Base   string
+	Rates  map[string]float64
+	Source string
+	Time   time.Time
+}
+
+// ErrorUnknownCurrency occurs when a provider doesn't have a rate for a currency
+type ErrorUnknownCurrency struct {
+	Code string
+}
+
+func (err ErrorUnknownCurrency) Error() string {
+	return fmt.Sprintf("No exchange rate for %s", err.Code)
+}
+
+// rate is how much of a currency one of the base is worth
+func (r Rates) rate(code string) (float64, bool) {
+	if code == r.Base {
+		return 1, true
+	}
+	v, ok := r.Rates[code]
+	return v, ok && v != 0
+}
+
+// cross works out the rate between two currencies in the table, through its base
+func (r Rates) cross(from, to string) (exchangeRate, bool) {
+	fromRate, ok := r.rate(from)
+	if !ok {
+		return exchangeRate{}, false
+	}
+	toRate, ok := r.rate(to)
+	if !ok {
+		return exchangeRate{}, false
+	}
+	return exchangeRate{Rate: toRate / fromRate, Source: r.Source, Time: r.Time, Base: r.Base}, true
+}
+
+// rebase works out the rates from base to each of the symbols from rates with a different base,
+// e.g. USD to JPY from EUR to USD and EUR to JPY. No symbols means every currency in the rates
+func (r Rates) rebase(base string, symbols []string) (Rates, error) {
+	if symbols == nil {
+		symbols = append(symbols, r.Base)
+		for code := range r.Rates {
+			symbols = append(symbols, code)
+		}
+	}
+	baseRate, ok := r.rate(base)
+	if !ok {
+		return Rates{}, ErrorUnknownCurrency{base}
+	}
+	rebased := Rates{Base: base, Rates: make(map[string]float64, len(symbols)), Source: r.Source, Time: r.Time}
+	for _, symbol := range symbols {
+		symbolRate, ok := r.rate(symbol)
+		if !ok {
+			return Rates{}, ErrorUnknownCurrency{symbol}
+		}
+		rebased.Rates[symbol] = symbolRate / baseRate
+	}
+	return rebased, nil
+}
+
+// ChainProvider asks each of its providers in turn until one of them answers
+type ChainProvider []RateProvider
+
+func (c ChainProvider) Name() string {
+	var names []string
+	for _, provider := range c {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Currencies lists the currencies of every provider that answers, so failing over doesn't lose any
+func (c ChainProvider) Currencies() (map[string]string, error) {
+	var errs []error
+	currencies := map[string]string{}
+	for _, provider := range c {
+		names, err := provider.Currencies()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+		for code, name := range names {
+			if _, ok := currencies[code]; !ok || currencies[code] == code {
+				currencies[code] = name
+			}
+		}
+	}
+	if len(currencies) == 0 {
+		return nil, errors.Join(append(errs, ErrorCurrencyService)...)
+	}
+	return currencies, nil
+}
+
+func (c ChainProvider) Rates(base string, symbols []string) (Rates, error) {
+	var errs []error
+	for _, provider := range c {
+		rates, err := provider.Rates(base, symbols)
+		if err == nil && len(rates.Rates) == 0 {
+			err = ErrorNoRates
+		}
+		if err == nil {
+			return rates, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return Rates{}, errors.Join(append(errs, ErrorCurrencyService)...)
+}
+
+// StaticProvider reads rates from a JSON file, e.g. for when there's no network
+//
+//	{"base": "EUR", "time": "2024-03-01T00:00:00Z", "rates": {"USD": 1.08}, "names": {"USD": "US Dollar"}}
+type StaticProvider struct {
+	Path string
+}
+
+type staticRates struct {
+	Base  string             `json:"base"`
+	Time  time.Time          `json:"time"`
+	Rates map[string]float64 `json:"rates"`
+	Names map[string]string  `json:"names"`
+}
+
+func (s StaticProvider) Name() string {
+	return s.Path
+}
+
+func (s StaticProvider) read() (staticRates, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return staticRates{}, err
+	}
+	var rates staticRates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return staticRates{}, fmt.Errorf("unable to decode rates file: %w", err)
+	}
+	return rates, nil
+}
+
+func (s StaticProvider) Currencies() (map[string]string, error) {
+	rates, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	currencies := map[string]string{rates.Base: rates.Names[rates.Base]}
+	for code := range rates.Rates {
+		currencies[code] = rates.Names[code]
+	}
+	for code, name := range currencies {
+		if name == "" {
+			currencies[code] = code
+		}
+	}
+	return currencies, nil
+}
+
+func (s StaticProvider) Rates(base string, symbols []string) (Rates, error) {
+	rates, err := s.read()
+	if err != nil {
+		return Rates{}, err
+	}
+	return Rates{Base: rates.Base, Rates: rates.Rates, Source: s.Name(), Time: rates.Time}.rebase(base, symbols)
+}
+
+// defaultClient is the client of providers that have none.
+// It has a timeout so a service that stops answering can't hold up conversions
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// httpClient is the client a provider uses, or defaultClient if it has none
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return defaultClient
+	}
+	return client
+}
+
+// getBody fetches a URL, failing on HTTP errors
+func getBody(client *http.Client, url string) ([]byte, error) {
+	resp, err := httpClient(client).Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("Error calling currency service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("currency service returned %s: %s", resp.Status, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,396 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ecbTestFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<Cube>
+		<Cube time="2024-03-01">
+			<Cube currency="USD" rate="1.25"/>
+			<Cube currency="JPY" rate="150"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+// fakeProvider is a rate provider with fixed rates, counting how often it's asked
+type fakeProvider struct {
+	name  string
+	rates Rates
+	err   error
+	calls *int
+}
+
+func (f fakeProvider) Name() string {
+	return f.name
+}
+
+func (f fakeProvider) Currencies() (map[string]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	currencies := map[string]string{f.rates.Base: f.rates.Base}
+	for code := range f.rates.Rates {
+		currencies[code] = code
+	}
+	return currencies, nil
+}
+
+func (f fakeProvider) Rates(base string, symbols []string) (Rates, error) {
+	if f.calls != nil {
+		*f.calls++
+	}
+	if f.err != nil {
+		return Rates{}, f.err
+	}
+	return f.rates.rebase(base, symbols)
+}
+
+// emptyProvider is a rate provider that answers without any rates
+type emptyProvider struct {
+	fakeProvider
+}
+
+func (emptyProvider) Rates(base string, symbols []string) (Rates, error) {
+	return Rates{Base: base, Rates: map[string]float64{}}, nil
+}
+
+// withCurrencies registers currencies for a test, and removes the ones it added when the test ends
+func withCurrencies(t *testing.T, currencies map[string]string) {
+	t.Helper()
+	unitLock.RLock()
+	existing := map[string]bool{}
+	for code := range currencyUnits {
+		existing[code] = true
+	}
+	unitLock.RUnlock()
+
+	registerCurrencies(currencies)
+	t.Cleanup(func() {
+		unitLock.Lock()
+		defer unitLock.Unlock()
+		added := map[UnitType]bool{}
+		for code, unit := range currencyUnits {
+			if !existing[code] {
+				added[unit] = true
+				delete(currencyUnits, code)
+				delete(supportedUnits, unit)
+			}
+		}
+		for _, names := range []map[string]UnitType{unitAliasMap, unitSymbolMap} {
+			for name, unit := range names {
+				if added[unit] {
+					delete(names, name)
+				}
+			}
+		}
+		refreshUnitMaps()
+	})
+}
+
+func checkRates(t *testing.T, rates Rates, want map[string]float64) {
+	t.Helper()
+	if len(rates.Rates) != len(want) {
+		t.Errorf("Rates() = %v, want %v", rates.Rates, want)
+	}
+	for code, rate := range want {
+		if !approx(rates.Rates[code], rate) {
+			t.Errorf("Rates()[%s] = %v, want %v", code, rates.Rates[code], rate)
+		}
+	}
+}
+
+func TestECBProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ecbTestFeed)
+	}))
+	defer server.Close()
+	provider := ECBProvider{URL: server.URL}
+
+	currencies, err := provider.Currencies()
+	if err != nil {
+		t.Fatalf("Currencies() error = %v", err)
+	}
+	for _, code := range []string{"EUR", "USD", "JPY"} {
+		if _, ok := currencies[code]; !ok {
+			t.Errorf("Currencies() is missing %s", code)
+		}
+	}
+
+	rates, err := provider.Rates("USD", []string{"EUR", "JPY"})
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	checkRates(t, rates, map[string]float64{"EUR": 0.8, "JPY": 120})
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !rates.Time.Equal(want) {
+		t.Errorf("Rates() time = %v, want %v", rates.Time, want)
+	}
+
+	if _, err := provider.Rates("EUR", []string{"XYZ"}); !errors.As(err, &ErrorUnknownCurrency{}) {
+		t.Errorf("Rates() error = %v, want ErrorUnknownCurrency", err)
+	}
+}
+
+func TestOpenExchangeRatesProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("app_id") != "secret" {
+			http.Error(w, "invalid app_id", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest.json":
+			fmt.Fprint(w, `{"timestamp": 1709251200, "base": "USD", "rates": {"USD": 1, "EUR": 0.8, "GBP": 0.5}}`)
+		case "/currencies.json":
+			fmt.Fprint(w, `{"USD": "United States Dollar", "EUR": "Euro", "GBP": "British Pound Sterling"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	provider := OpenExchangeRatesProvider{URL: server.URL, AppID: "secret"}
+
+	currencies, err := provider.Currencies()
+	if err != nil {
+		t.Fatalf("Currencies() error = %v", err)
+	}
+	if currencies["EUR"] != "Euro" {
+		t.Errorf("Currencies()[EUR] = %q, want Euro", currencies["EUR"])
+	}
+
+	// The free plan's rates are from USD, so they're rebased
+	rates, err := provider.Rates("EUR", []string{"GBP", "USD"})
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	if rates.Base != "EUR" {
+		t.Errorf("Rates() base = %s, want EUR", rates.Base)
+	}
+	checkRates(t, rates, map[string]float64{"GBP": 0.625, "USD": 1.25})
+
+	all, err := provider.Rates("GBP", nil)
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	checkRates(t, all, map[string]float64{"GBP": 1, "USD": 2, "EUR": 1.6})
+
+	if _, err := (OpenExchangeRatesProvider{URL: server.URL}).Rates("EUR", nil); err == nil {
+		t.Error("Rates() without an app ID succeeded")
+	}
+}
+
+func TestCurrConvProvider(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apiKey") != "secret" {
+			http.Error(w, "invalid apiKey", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/convert":
+			q := r.URL.Query().Get("q")
+			queries = append(queries, q)
+			rates := map[string]string{"USD_EUR": "0.8", "USD_GBP": "0.5"}
+			if rate, ok := rates[q]; ok {
+				fmt.Fprintf(w, `{%q: %s}`, q, rate)
+			} else {
+				fmt.Fprint(w, `{}`)
+			}
+		case "/currencies":
+			fmt.Fprint(w, `{"results": {"EUR": {"id": "EUR", "currencyName": "Euro"}, "USD": {"id": "USD", "currencyName": "US Dollar"}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	provider := CurrConvProvider{URL: server.URL, APIKey: "secret"}
+
+	currencies, err := provider.Currencies()
+	if err != nil {
+		t.Fatalf("Currencies() error = %v", err)
+	}
+	if currencies["USD"] != "US Dollar" {
+		t.Errorf("Currencies()[USD] = %q, want US Dollar", currencies["USD"])
+	}
+
+	rates, err := provider.Rates("USD", []string{"EUR", "GBP"})
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	checkRates(t, rates, map[string]float64{"EUR": 0.8, "GBP": 0.5})
+	if strings.Join(queries, ",") != "USD_EUR,USD_GBP" {
+		t.Errorf("queries = %v, want one per symbol", queries)
+	}
+
+	if _, err := provider.Rates("USD", []string{"JPY"}); err == nil {
+		t.Error("Rates() of an unknown currency succeeded")
+	}
+	// It can only convert pairs, so it can't make a rate table
+	if _, err := provider.Rates("USD", nil); !errors.Is(err, ErrorNoRateTable) {
+		t.Errorf("Rates() of every currency error = %v, want ErrorNoRateTable", err)
+	}
+	if _, err := (CurrConvProvider{URL: server.URL}).Rates("USD", []string{"EUR"}); !errors.Is(err, ErrorCurrencyService) {
+		t.Errorf("Rates() without an API key error = %v, want ErrorCurrencyService", err)
+	}
+}
+
+func TestStaticProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	data := `{"base": "EUR", "time": "2024-03-01T00:00:00Z", "rates": {"USD": 1.25, "JPY": 150}, "names": {"USD": "US Dollar"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	provider := StaticProvider{Path: path}
+
+	currencies, err := provider.Currencies()
+	if err != nil {
+		t.Fatalf("Currencies() error = %v", err)
+	}
+	// Currencies without names are named by their code
+	want := map[string]string{"EUR": "EUR", "USD": "US Dollar", "JPY": "JPY"}
+	for code, name := range want {
+		if currencies[code] != name {
+			t.Errorf("Currencies()[%s] = %q, want %q", code, currencies[code], name)
+		}
+	}
+
+	rates, err := provider.Rates("USD", []string{"JPY"})
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	checkRates(t, rates, map[string]float64{"JPY": 120})
+	if rates.Source != path {
+		t.Errorf("Rates() source = %s, want %s", rates.Source, path)
+	}
+
+	if _, err := (StaticProvider{Path: filepath.Join(t.TempDir(), "missing.json")}).Rates("EUR", nil); err == nil {
+		t.Error("Rates() of a missing file succeeded")
+	}
+}
+
+func TestChainProvider(t *testing.T) {
+	var firstCalls, secondCalls int
+	down := fakeProvider{name: "down", err: errors.New("connection refused"), calls: &firstCalls}
+	up := fakeProvider{name: "up", rates: Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.25}}, calls: &secondCalls}
+
+	rates, err := ChainProvider{down, up}.Rates("EUR", []string{"USD"})
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	checkRates(t, rates, map[string]float64{"USD": 1.25})
+	if firstCalls != 1 || secondCalls != 1 {
+		t.Errorf("calls = %d, %d, want each provider asked once", firstCalls, secondCalls)
+	}
+
+	// A provider that answers isn't failed over
+	if _, err := (ChainProvider{up, down}).Rates("EUR", []string{"USD"}); err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	if firstCalls != 1 {
+		t.Errorf("failing provider asked %d times, want 1", firstCalls)
+	}
+
+	other := fakeProvider{name: "other", err: errors.New("rate limited")}
+	_, err = ChainProvider{down, other}.Rates("EUR", []string{"USD"})
+	if !errors.Is(err, ErrorCurrencyService) {
+		t.Errorf("Rates() error = %v, want ErrorCurrencyService", err)
+	}
+	for _, part := range []string{"down: connection refused", "other: rate limited"} {
+		if err == nil || !strings.Contains(err.Error(), part) {
+			t.Errorf("Rates() error = %v, want it to contain %q", err, part)
+		}
+	}
+
+	// Answering without any rates is failing
+	if _, err := (ChainProvider{emptyProvider{}}).Rates("EUR", nil); !errors.Is(err, ErrorNoRates) {
+		t.Errorf("Rates() error = %v, want ErrorNoRates", err)
+	}
+	rates, err = ChainProvider{fakeProvider{name: "none", err: ErrorNoRateTable}, up}.Rates("EUR", nil)
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	checkRates(t, rates, map[string]float64{"EUR": 1, "USD": 1.25})
+
+	currencies, err := ChainProvider{down, up}.Currencies()
+	if err != nil {
+		t.Fatalf("Currencies() error = %v", err)
+	}
+	if _, ok := currencies["USD"]; !ok {
+		t.Errorf("Currencies() = %v, want USD", currencies)
+	}
+	if _, err := (ChainProvider{down, other}).Currencies(); !errors.Is(err, ErrorCurrencyService) {
+		t.Errorf("Currencies() error = %v, want ErrorCurrencyService", err)
+	}
+}
+
+func TestConvertListFetchesRateTableOnce(t *testing.T) {
+	var ecbHits, currConvHits int
+	ecb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ecbHits++
+		fmt.Fprint(w, `<Envelope><Cube><Cube time="2024-03-01">
+			<Cube currency="USD" rate="1.25"/><Cube currency="GBP" rate="0.85"/><Cube currency="JPY" rate="150"/>
+		</Cube></Cube></Envelope>`)
+	}))
+	defer ecb.Close()
+	currConv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currConvHits++
+		q := r.URL.Query().Get("q")
+		fmt.Fprintf(w, `{%q: 1}`, q)
+	}))
+	defer currConv.Close()
+
+	SetRateProviders(CurrConvProvider{URL: currConv.URL, APIKey: "secret"}, ECBProvider{URL: ecb.URL})
+	withCurrencies(t, map[string]string{"EUR": "Euro", "USD": "US Dollar", "GBP": "British Pound", "JPY": "Japanese Yen"})
+	currencyCache.Flush()
+	defer func() {
+		rateProvider = nil
+		currencyCache.Flush()
+	}()
+
+	reply := ProcessIn(Scope{}, "100 USD to EUR, GBP, JPY")
+	for _, want := range []string{"80.00 EUR", "68.00 GBP", "12000 JPY"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("ProcessIn() = %q, want it to contain %q", reply, want)
+		}
+	}
+	// Every rate is worked out from the ECB's table, rather than asking CurrConv for each pair
+	if ecbHits != 1 || currConvHits != 0 {
+		t.Errorf("upstream hits = %d ECB, %d CurrConv, want 1 and 0", ecbHits, currConvHits)
+	}
+}
+
+func TestFailedRatesBackOff(t *testing.T) {
+	calls := 0
+	SetRateProviders(fakeProvider{name: "Down", err: errors.New("timeout"), calls: &calls})
+	withCurrencies(t, map[string]string{"EUR": "Euro", "USD": "US Dollar"})
+	currencyCache.Flush()
+	defer func() {
+		rateProvider = nil
+		currencyCache.Flush()
+	}()
+
+	for i := 0; i < 3; i++ {
+		ProcessIn(Scope{}, "100 USD to EUR")
+	}
+	// The table and the pair are each asked for once, not on every conversion
+	if calls != 2 {
+		t.Errorf("provider calls = %d, want 2", calls)
+	}
+}
+
+func TestFetchRateUnknownCurrency(t *testing.T) {
+	SetRateProviders(emptyProvider{})
+	defer func() { rateProvider = nil }()
+
+	if _, err := fetchRate("EUR", "XYZ"); !errors.As(err, &ErrorUnknownCurrency{}) {
+		t.Errorf("fetchRate() error = %v, want ErrorUnknownCurrency", err)
+	}
+}
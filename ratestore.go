@@ -0,0 +1,249 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// savedRates are the currencies and exchange rates last fetched, so they survive restarts
+// and can be used while the rate providers are down
+type savedRates struct {
+	Currencies     map[string]string       `json:"currencies"`
+	CurrenciesTime time.Time               `json:"currencies_time"`
+	Rates          map[string]exchangeRate `json:"rates"`
+	// Historical rates never change, so they are kept for good
+	Historical map[string]exchangeRate `json:"historical"`
+}
+
+// rateStoreSaveDelay is how long changes to the store wait to be saved, so a burst of new rates is one write
+var rateStoreSaveDelay = 5 * time.Second
+
+// ErrorRefreshInterval is returned for a rate refresh interval that isn't positive
+var ErrorRefreshInterval = errors.New("rate refresh interval must be positive")
+
+var (
+	rateStoreLock sync.Mutex
+	rateStorePath string
+	// rateStoreSave is the pending save of the store, nil if there are no unsaved changes
+	rateStoreSave *time.Timer
+	rateStore     = savedRates{
+		Currencies: map[string]string{},
+		Rates:      map[string]exchangeRate{},
+		Historical: map[string]exchangeRate{},
+	}
+)
+
+// LoadRateStore loads saved currencies and exchange rates from a file, and saves new ones back to it.
+// The saved currencies can be converted straight away, without waiting for the rate providers
+func LoadRateStore(path string) error {
+	rateStoreLock.Lock()
+	rateStorePath = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		rateStoreLock.Unlock()
+		return nil
+	}
+	if err != nil {
+		rateStoreLock.Unlock()
+		return err
+	}
+
+	loaded := savedRates{}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		rateStoreLock.Unlock()
+		return err
+	}
+	if loaded.Currencies != nil {
+		rateStore.Currencies = loaded.Currencies
+		rateStore.CurrenciesTime = loaded.CurrenciesTime
+	}
+	if loaded.Rates != nil {
+		rateStore.Rates = loaded.Rates
+	}
+	if loaded.Historical != nil {
+		rateStore.Historical = loaded.Historical
+	}
+	currencies := rateStore.Currencies
+	rateStoreLock.Unlock()
+
+	slog.Info("Loaded stored rates", "path", path, "currencies", len(currencies), "rates", len(loaded.Rates))
+	registerCurrencies(currencies)
+	return nil
+}
+
+// RefreshRates refetches every stored rate in the background, every interval
+func RefreshRates(interval time.Duration) error {
+	if interval <= 0 {
+		return ErrorRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			refreshStoredRates()
+		}
+	}()
+	return nil
+}
+
+// refreshStoredRates refetches the rate table and works out the stored rates from it,
+// asking for all the rates from a currency at once for any it doesn't have
+func refreshStoredRates() {
+	if rateProvider == nil {
+		return
+	}
+	table, err := fetchRateTable()
+	if err != nil {
+		slog.Warn("Unable to refresh rate table", "base", rateBase, "err", err)
+	}
+	symbols := map[string][]string{}
+	crosses := map[string]exchangeRate{}
+	rateStoreLock.Lock()
+	for op := range rateStore.Rates {
+		from, to, ok := strings.Cut(op, "_")
+		if !ok {
+			continue
+		}
+		if r, ok := table.cross(from, to); ok {
+			crosses[op] = r
+		} else {
+			symbols[from] = append(symbols[from], to)
+		}
+	}
+	rateStoreLock.Unlock()
+
+	if len(crosses) > 0 {
+		// Pairs fetched on their own before the table had them are replaced, so they aren't left stale in the cache
+		for op, r := range crosses {
+			currencyCache.Set(op, r, cache.DefaultExpiration)
+		}
+		storeRates(crosses)
+	}
+
+	for from, to := range symbols {
+		rates, err := rateProvider.Rates(from, to)
+		if err != nil {
+			slog.Warn("Unable to refresh rates", "from", from, "err", err)
+			continue
+		}
+		for _, symbol := range to {
+			rate, ok := rates.rate(symbol)
+			if !ok {
+				slog.Warn("Unable to refresh rate", "from", from, "err", ErrorUnknownCurrency{symbol})
+				continue
+			}
+			op := from + "_" + symbol
+			r := exchangeRate{Rate: rate, Source: rates.Source, Time: rates.Time}
+			currencyCache.Set(op, r, cache.DefaultExpiration)
+			storeRate(op, r)
+		}
+	}
+	slog.Info("Refreshed stored rates", "crosses", len(crosses), "bases", len(symbols))
+}
+
+// storedRate finds the last rate fetched for a pair of currencies, however old it is
+func storedRate(op string) (exchangeRate, bool) {
+	rateStoreLock.Lock()
+	defer rateStoreLock.Unlock()
+	r, ok := rateStore.Rates[op]
+	return r, ok
+}
+
+func storeRate(op string, r exchangeRate) {
+	storeRates(map[string]exchangeRate{op: r})
+}
+
+// storeRates stores several rates with one write
+func storeRates(rates map[string]exchangeRate) {
+	rateStoreLock.Lock()
+	defer rateStoreLock.Unlock()
+	for op, r := range rates {
+		rateStore.Rates[op] = r
+	}
+	saveRateStore()
+}
+
+// storedHistoricalRate finds a historical rate fetched before
+func storedHistoricalRate(key string) (exchangeRate, bool) {
+	rateStoreLock.Lock()
+	defer rateStoreLock.Unlock()
+	r, ok := rateStore.Historical[key]
+	return r, ok
+}
+
+func storeHistoricalRate(key string, r exchangeRate) {
+	rateStoreLock.Lock()
+	defer rateStoreLock.Unlock()
+	rateStore.Historical[key] = r
+	saveRateStore()
+}
+
+// storedCurrencies are the currencies last fetched
+func storedCurrencies() map[string]string {
+	rateStoreLock.Lock()
+	defer rateStoreLock.Unlock()
+	return rateStore.Currencies
+}
+
+func storeCurrencies(currencies map[string]string) {
+	rateStoreLock.Lock()
+	defer rateStoreLock.Unlock()
+	rateStore.Currencies = currencies
+	rateStore.CurrenciesTime = time.Now().UTC()
+	saveRateStore()
+}
+
+// saveRateStore saves the store to its file after rateStoreSaveDelay, if it has one,
+// along with any other changes made in the meantime.
+// The lock must be held
+func saveRateStore() {
+	if rateStorePath == "" || rateStoreSave != nil {
+		return
+	}
+	rateStoreSave = time.AfterFunc(rateStoreSaveDelay, func() {
+		rateStoreLock.Lock()
+		defer rateStoreLock.Unlock()
+		// It may have been flushed while this waited for the lock
+		if rateStoreSave != nil {
+			writeRateStore()
+		}
+	})
+}
+
+// FlushRateStore saves any changes to the store straight away, e.g. before exiting
+func FlushRateStore() {
+	rateStoreLock.Lock()
+	defer rateStoreLock.Unlock()
+	if rateStoreSave != nil {
+		rateStoreSave.Stop()
+		writeRateStore()
+	}
+}
+
+// writeRateStore writes the store to its file.
+// The lock must be held
+func writeRateStore() {
+	rateStoreSave = nil
+	data, err := json.MarshalIndent(rateStore, "", "  ")
+	if err != nil {
+		slog.Error("Unable to encode rates", "err", err)
+		return
+	}
+	// Written to a temporary file first so a crash can't leave half a file
+	tmp := rateStorePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		slog.Error("Unable to save rates", "path", rateStorePath, "err", err)
+		return
+	}
+	if err := os.Rename(tmp, rateStorePath); err != nil {
+		slog.Error("Unable to save rates", "path", rateStorePath, "err", err)
+	}
+}
@@ -0,0 +1,129 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// referenceObject is a familiar object to compare a value against
+type referenceObject struct {
+	singular, plural string
+	size             UnitVal
+}
+
+// relatables are reference objects for each dimension where ratios make sense
+var relatables = map[UnitDimension][]referenceObject{
+	UnitDimensionLength: {
+		{"human hair width", "human hair widths", Millimeter.FromFloat(0.07)},
+		{"credit card", "credit cards", Millimeter.FromFloat(85.6)},
+		{"banana", "bananas", Centimeter.FromFloat(18)},
+		{"adult human", "adult humans", Meter.FromFloat(1.7)},
+		{"school bus", "school buses", Meter.FromFloat(12)},
+		{"blue whale", "blue whales", Meter.FromFloat(25)},
+		{"Olympic pool", "Olympic pools", Meter.FromFloat(50)},
+		{"football field", "football fields", Meter.FromFloat(105)},
+		{"Eiffel Tower", "Eiffel Towers", Meter.FromFloat(330)},
+		{"Mount Everest", "Mount Everests", Meter.FromFloat(8849)},
+		{"marathon", "marathons", Kilometer.FromFloat(42.195)},
+		{"trip around the Earth", "trips around the Earth", Kilometer.FromFloat(40075)},
+		{"trip to the Moon", "trips to the Moon", Kilometer.FromFloat(384400)},
+	},
+	UnitDimensionMass: {
+		{"paperclip", "paperclips", Gram.FromFloat(1)},
+		{"banana", "bananas", Gram.FromFloat(120)},
+		{"bowling ball", "bowling balls", Kilogram.FromFloat(7)},
+		{"adult human", "adult humans", Kilogram.FromFloat(70)},
+		{"car", "cars", Kilogram.FromFloat(1500)},
+		{"elephant", "elephants", Kilogram.FromFloat(6000)},
+		{"blue whale", "blue whales", Kilogram.FromFloat(150000)},
+		{"Eiffel Tower", "Eiffel Towers", Kilogram.FromFloat(7.3e6)},
+	},
+	UnitDimensionVolume: {
+		{"raindrop", "raindrops", Milliliter.FromFloat(0.05)},
+		{"soda can", "soda cans", Milliliter.FromFloat(355)},
+		{"bucket", "buckets", Liter.FromFloat(10)},
+		{"bathtub", "bathtubs", Liter.FromFloat(150)},
+		{"hot tub", "hot tubs", Liter.FromFloat(1500)},
+		{"tanker truck", "tanker trucks", Liter.FromFloat(30000)},
+		{"Olympic pool", "Olympic pools", Liter.FromFloat(2.5e6)},
+	},
+	UnitDimensionDuration: {
+		{"blink", "blinks", Second.FromFloat(0.3)},
+		{"song", "songs", Minute.FromFloat(3.5)},
+		{"football match", "football matches", Minute.FromFloat(90)},
+		{"movie", "movies", Hour.FromFloat(2)},
+		{"working week", "working weeks", Hour.FromFloat(40)},
+		{"human lifetime", "human lifetimes", Year.FromFloat(73)},
+	},
+	UnitDimensionSpeed: {
+		{"snail", "snails", KilometersPerHour.FromFloat(0.05)},
+		{"walking pace", "walking paces", KilometersPerHour.FromFloat(5)},
+		{"Usain Bolt", "Usain Bolts", KilometersPerHour.FromFloat(37.6)},
+		{"cheetah", "cheetahs", KilometersPerHour.FromFloat(110)},
+		{"jet airliner", "jet airliners", KilometersPerHour.FromFloat(900)},
+		{"speed of sound", "speeds of sound", KilometersPerHour.FromFloat(1235)},
+	},
+}
+
+// maxRelatables is how many reference objects a value is compared to
+const maxRelatables = 3
+
+// Relatable phrases a value in terms of the reference objects closest to it in size
+func Relatable(v UnitVal) (string, error) {
+	objects := relatables[v.Unit().Dimension()]
+	if len(objects) == 0 {
+		return "", fmt.Errorf("Nothing relatable to compare %s to", v.Unit())
+	}
+
+	type ratio struct {
+		object referenceObject
+		n      float64
+	}
+	var ratios []ratio
+	for _, object := range objects {
+		c, err := v.Convert(object.size.Unit())
+		if err != nil {
+			return "", err
+		}
+		ratios = append(ratios, ratio{object, magnitude(c) / magnitude(object.size)})
+	}
+	sort.SliceStable(ratios, func(i, j int) bool {
+		return relatability(ratios[i].n) < relatability(ratios[j].n)
+	})
+	if len(ratios) > maxRelatables {
+		ratios = ratios[:maxRelatables]
+	}
+
+	phrases := []string{v.String()}
+	for _, r := range ratios {
+		n, name := formatCount(r.n), r.object.plural
+		if n == "1" {
+			name = r.object.singular
+		}
+		phrases = append(phrases, fmt.Sprintf("%s %s", n, name))
+	}
+	return strings.Join(phrases, " ≈ "), nil
+}
+
+// relatability scores how far a count is from 1, where fractions of an object count as further
+func relatability(n float64) float64 {
+	score := math.Log10(math.Abs(n))
+	if score < 0 {
+		return -2 * score
+	}
+	return score
+}
+
+// formatCount formats a rough count of objects, e.g. 2.7, 45 or 1300
+func formatCount(n float64) string {
+	switch {
+	case math.Abs(n) >= 1e6:
+		return fmt.Sprintf("%.2g", n)
+	case math.Abs(n) >= 100:
+		return fmt.Sprintf("%.0f", n)
+	default:
+		return fmt.Sprintf("%.2g", n)
+	}
+}
@@ -0,0 +1,139 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Scope is where a command was sent from, so guilds and users can have their own defaults
+type Scope struct {
+	GuildID string
+	UserID  string
+}
+
+// savedSettings are defaults set per guild and per user, by setting key
+type savedSettings struct {
+	Guilds map[string]map[string]string `json:"guilds"`
+	Users  map[string]map[string]string `json:"users"`
+}
+
+var (
+	settingsLock sync.RWMutex
+	settingsPath string
+	settings     = savedSettings{
+		Guilds: map[string]map[string]string{},
+		Users:  map[string]map[string]string{},
+	}
+)
+
+// LoadSettings loads guild and user settings from a file, and saves changes back to it.
+// A missing file is created on the first change. If the file can't be read, changes aren't saved,
+// so they can't overwrite it
+func LoadSettings(path string) error {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		settingsPath = path
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	loaded := savedSettings{}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return err
+	}
+	if loaded.Guilds != nil {
+		settings.Guilds = loaded.Guilds
+	}
+	if loaded.Users != nil {
+		settings.Users = loaded.Users
+	}
+	settingsPath = path
+	return nil
+}
+
+// lookupSetting finds a setting for the user, falling back to their guild
+func lookupSetting(scope Scope, key string) (string, bool) {
+	settingsLock.RLock()
+	defer settingsLock.RUnlock()
+
+	if v, ok := settings.Users[scope.UserID][key]; ok && scope.UserID != "" {
+		return v, true
+	}
+	if v, ok := settings.Guilds[scope.GuildID][key]; ok && scope.GuildID != "" {
+		return v, true
+	}
+	return "", false
+}
+
+// scopeKind is whether a setting is a guild's or a user's
+type scopeKind int
+
+const (
+	guildScope scopeKind = iota
+	userScope
+)
+
+// settings are the settings of every guild or every user. The lock must be held,
+// since LoadSettings replaces them
+func (kind scopeKind) settings() map[string]map[string]string {
+	if kind == userScope {
+		return settings.Users
+	}
+	return settings.Guilds
+}
+
+// setSetting sets or, with an empty value, removes a guild's or user's setting and saves the settings.
+// The change is undone if it can't be saved
+func setSetting(kind scopeKind, id, key, value string) error {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+
+	scoped := kind.settings()
+	previous, existed := scoped[id][key]
+	if value == "" {
+		delete(scoped[id], key)
+	} else {
+		if scoped[id] == nil {
+			scoped[id] = map[string]string{}
+		}
+		scoped[id][key] = value
+	}
+
+	if err := saveSettings(); err != nil {
+		slog.Error("Unable to save settings", "path", settingsPath, "err", err)
+		if existed {
+			scoped[id][key] = previous
+		} else {
+			delete(scoped[id], key)
+		}
+		return err
+	}
+	return nil
+}
+
+// saveSettings writes the settings to their file, if they have one.
+// The lock must be held
+func saveSettings() error {
+	if settingsPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Written to a temporary file first so a crash can't leave half a file
+	tmp := settingsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, settingsPath)
+}
@@ -0,0 +1,64 @@
+package convert
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	saved, savedPath := settings, settingsPath
+	defer func() {
+		settingsLock.Lock()
+		settings, settingsPath = saved, savedPath
+		settingsLock.Unlock()
+	}()
+
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(`{"guilds": {"g": {"tz": "Europe/Paris"}}, "users": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadSettings(path); err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+
+	// Settings are changed while others are loaded and read
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetUserHomeZone("u", "Asia/Tokyo")
+		}()
+		go func() {
+			defer wg.Done()
+			LoadSettings(path)
+			lookupSetting(Scope{GuildID: "g", UserID: "u"}, homeZoneKey)
+		}()
+	}
+	wg.Wait()
+
+	if err := SetUserHomeZone("u", "Asia/Tokyo"); err != nil {
+		t.Fatalf("SetUserHomeZone() error = %v", err)
+	}
+	if got, _ := lookupSetting(Scope{GuildID: "g", UserID: "u"}, homeZoneKey); got != "Asia/Tokyo" {
+		t.Errorf("user home zone = %q, want Asia/Tokyo", got)
+	}
+	if got, _ := lookupSetting(Scope{GuildID: "g"}, homeZoneKey); got != "Europe/Paris" {
+		t.Errorf("guild home zone = %q, want Europe/Paris", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written savedSettings
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Users["u"][homeZoneKey] != "Asia/Tokyo" {
+		t.Errorf("saved settings = %s, want the user's home zone", data)
+	}
+}
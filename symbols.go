@@ -0,0 +1,90 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ambiguousSymbols are currency symbols used by several currencies, with the currencies they can mean.
+// The first is the default, and guilds and users can pick another.
+// Prefixed symbols like C$ always mean the same currency, so they are aliases instead
+var ambiguousSymbols = map[string][]string{
+	"$":  {"USD", "CAD", "AUD", "NZD", "HKD", "SGD", "MXN"},
+	"kr": {"SEK", "NOK", "DKK", "ISK"},
+	"¥":  {"JPY", "CNY"},
+	"£":  {"GBP", "EGP", "GIP", "FKP"},
+}
+
+// symbolWords are words that are as ambiguous as a symbol, by the symbol they follow
+var symbolWords = map[string]string{
+	"dollar":  "$",
+	"dollars": "$",
+}
+
+// ambiguousSymbol finds the ambiguous symbol a unit name is, if it is one
+func ambiguousSymbol(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if symbol, ok := symbolWords[name]; ok {
+		return symbol, true
+	}
+	_, ok := ambiguousSymbols[name]
+	return name, ok
+}
+
+func symbolKey(symbol string) string {
+	return "symbol:" + symbol
+}
+
+// lookupUnitIn finds a unit, with ambiguous currency symbols meaning the currency the user or their guild picked
+func lookupUnitIn(scope Scope, name string) (UnitType, bool) {
+	if symbol, ok := ambiguousSymbol(name); ok {
+		if code, ok := lookupSetting(scope, symbolKey(symbol)); ok {
+			return LookupUnit(code)
+		}
+	}
+	return LookupUnit(name)
+}
+
+// SetGuildSymbol sets which currency an ambiguous symbol means in a guild, e.g. $ to CAD.
+// An empty code goes back to the default
+func SetGuildSymbol(guildID, symbol, code string) error {
+	symbol, code, err := checkSymbol(symbol, code)
+	if err != nil {
+		return err
+	}
+	return setSetting(guildScope, guildID, symbolKey(symbol), code)
+}
+
+// SetUserSymbol sets which currency an ambiguous symbol means for a user, wherever they are.
+// An empty code goes back to their guild's default
+func SetUserSymbol(userID, symbol, code string) error {
+	symbol, code, err := checkSymbol(symbol, code)
+	if err != nil {
+		return err
+	}
+	return setSetting(userScope, userID, symbolKey(symbol), code)
+}
+
+// checkSymbol checks that a symbol is ambiguous and can mean a currency
+func checkSymbol(symbol, code string) (string, string, error) {
+	symbol, ok := ambiguousSymbol(symbol)
+	if !ok {
+		var symbols []string
+		for s := range ambiguousSymbols {
+			symbols = append(symbols, s)
+		}
+		sort.Strings(symbols)
+		return "", "", fmt.Errorf("%s isn't ambiguous, only %s can be set", symbol, strings.Join(symbols, ", "))
+	}
+	if code == "" {
+		return symbol, "", nil
+	}
+	code = strings.ToUpper(code)
+	for _, c := range ambiguousSymbols[symbol] {
+		if c == code {
+			return symbol, code, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s can mean %s, not %s", symbol, strings.Join(ambiguousSymbols[symbol], ", "), code)
+}
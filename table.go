@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	p "unit-bot/parser"
+)
+
+const (
+	// maxTableRows is the most rows a table can have, to keep replies short
+	maxTableRows = 50
+	// defaultTableRows is how many rows a table has when a range doesn't fit steps of 1
+	defaultTableRows = 11
+)
+
+// tableCommand converts a range of values, e.g. table 100-250 C step 25 to F
+type tableCommand struct {
+	span  tableSpan
+	steps tableSteps
+	to    string
+	csv   bool
+}
+
+type tableSpan struct {
+	lo, hi float64
+	unit   string
+}
+
+// tableSteps is either a step size or a number of rows
+type tableSteps struct {
+	step  float64
+	count int
+}
+
+var (
+	rangeSep      = p.Token(`(-|–|\.\.)`)
+	tableSpanExpr = p.Parse2(
+		p.Parse3(p.Float, rangeSep, p.Float, func(lo float64, _ string, hi float64) tableSpan { return tableSpan{lo: lo, hi: hi} }),
+		unitToken,
+		func(span tableSpan, u string) tableSpan { span.unit = u; return span },
+	)
+	tableStepsExpr = p.First(
+		p.Parse2(p.Atom(`step`), p.Float, func(_ string, step float64) tableSteps { return tableSteps{step: step} }),
+		p.Parse2(p.Atom(`count`), p.Int, func(_ string, count int) tableSteps { return tableSteps{count: count} }),
+	).Or(tableSteps{})
+	csvFlag   = p.Parse2(p.Atom(`as`).Opt(), p.Atom(`csv`), func(_, _ string) bool { return true }).Or(false)
+	tableExpr = p.Parse3(
+		p.Parse3(p.Atom(`table`), tableSpanExpr, tableStepsExpr, func(_ string, span tableSpan, steps tableSteps) tableCommand {
+			return tableCommand{span: span, steps: steps}
+		}),
+		target,
+		csvFlag,
+		func(cmd tableCommand, to string, csv bool) tableCommand { cmd.to, cmd.csv = to, csv; return cmd },
+	)
+)
+
+// values lists the values in the range, including both ends
+func (cmd tableCommand) values() ([]float64, error) {
+	lo, hi := cmd.span.lo, cmd.span.hi
+	step := cmd.steps.step
+	switch {
+	case cmd.steps.count == 1:
+		return []float64{lo}, nil
+	case cmd.steps.count > 1:
+		step = (hi - lo) / float64(cmd.steps.count-1)
+	case step == 0 && math.Abs(hi-lo) < maxTableRows:
+		step = 1
+	case step == 0:
+		step = (hi - lo) / (defaultTableRows - 1)
+	}
+	if step == 0 {
+		return []float64{lo}, nil
+	}
+	step = math.Copysign(step, hi-lo)
+
+	n := int(math.Floor((hi-lo)/step+1e-9)) + 1
+	if n > maxTableRows || n < 1 {
+		return nil, fmt.Errorf("Tables can have at most %d rows", maxTableRows)
+	}
+
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = lo + float64(i)*step
+	}
+	return values, nil
+}
+
+func (cmd tableCommand) run(scope Scope) string {
+	fromUnit, ok := lookupUnitIn(scope, cmd.span.unit)
+	if !ok {
+		return ErrorInvalidUnit{cmd.span.unit}.Error()
+	}
+	toUnit, ok := lookupUnitIn(scope, cmd.to)
+	if !ok {
+		return ErrorInvalidUnit{cmd.to}.Error()
+	}
+
+	values, err := cmd.values()
+	if err != nil {
+		return err.Error()
+	}
+
+	var rows []conversionRow
+	var csv strings.Builder
+	fmt.Fprintf(&csv, "```csv\n%s,%s\n", fromUnit, toUnit)
+	for _, v := range values {
+		from := fromUnit.FromFloat(v)
+		to, err := from.Convert(toUnit)
+		if err != nil {
+			return err.Error()
+		}
+		rows = append(rows, conversionRow{from: from.String(), result: to.String()})
+		fmt.Fprintf(&csv, "%.10g,%.10g\n", magnitude(from), magnitude(to))
+	}
+	csv.WriteString("```")
+
+	if cmd.csv {
+		return csv.String()
+	}
+	return annotate(formatRows(rows), fromUnit, toUnit)
+}
@@ -0,0 +1,11 @@
+package convert
+
+import "testing"
+
+func TestTables(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"table 100-250 C step 25 to F", "```\n100 °C = 212 °F\n125 °C = 257 °F\n150 °C = 302 °F\n175 °C = 347 °F\n200 °C = 392 °F\n225 °C = 437 °F\n250 °C = 482 °F\n```"},
+		{"table 1-4 km to mi", "```\n1 km = 0.621371 miles\n2 km = 1.24274 miles\n3 km = 1.86411 miles\n4 km = 2.48548 miles\n```"},
+		{"table 1-3 kg count 3 to lb csv", "```csv\nkg,lbs\n1,2.204622622\n2,4.409245244\n3,6.613867866\n```"},
+	})
+}
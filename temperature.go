@@ -11,3 +11,8 @@ var (
 	Fahrenheit = &TemperatureUnit{UnitDimensionTemperature, "°F", unit.FromFahrenheit, unit.Temperature.Fahrenheit}
 	Kelvin     = &TemperatureUnit{UnitDimensionTemperature, "K", unit.FromKelvin, unit.Temperature.Kelvin}
 )
+
+// hasOffset checks whether a unit's zero isn't absolute zero, so its values can't be added across units
+func hasOffset(u UnitType) bool {
+	return u == UnitType(Celsius) || u == UnitType(Fahrenheit)
+}
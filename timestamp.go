@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	p "unit-bot/parser"
+)
+
+// timestampStyles are the styles Discord can show a timestamp in, with what they look like
+var timestampStyles = []struct{ style, name string }{
+	{"t", "short time"},
+	{"T", "long time"},
+	{"d", "short date"},
+	{"D", "long date"},
+	{"f", "short date/time"},
+	{"F", "long date/time"},
+	{"R", "relative"},
+}
+
+// zonedTime is a wall clock time in a time zone, e.g. 8pm PST
+type zonedTime struct {
+	at   wallClock
+	zone string
+}
+
+var zonedTimeExpr = p.Parse2(wallExpr, zoneToken.Or(""), func(at wallClock, zone string) zonedTime { return zonedTime{at, zone} })
+
+// resolve finds the moment a zoned time refers to, in its time zone
+func (zt zonedTime) resolve(scope Scope) (time.Time, error) {
+	loc := time.UTC
+	if zt.zone != "" {
+		zone, err := LookupZone(scope, zt.zone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = zone
+	} else if !zt.at.now {
+		return time.Time{}, fmt.Errorf("Which time zone is %s in?", zt.at)
+	}
+	return zt.at.in(loc)
+}
+
+// String writes the wall clock time as it was given
+func (w wallClock) String() string {
+	if w.now {
+		return "now"
+	}
+	s := w.clock["hour"]
+	if w.clock["min"] != "" {
+		s += ":" + w.clock["min"]
+	}
+	s += w.clock["ampm"]
+	if w.date != "" {
+		s = w.date + " " + s
+	}
+	return s
+}
+
+// Timestamp makes Discord timestamps for a time in a time zone, e.g. 8pm PST, for the /timestamp command
+func Timestamp(scope Scope, when string) string {
+	zt, n, ok := zonedTimeExpr([]byte(when))
+	if !ok || strings.TrimSpace(when[n:]) != "" {
+		return "Usage: /timestamp [date] [time] [zone], e.g. 2024-03-01 8pm PST"
+	}
+	t, err := zt.resolve(scope)
+	if err != nil {
+		return err.Error()
+	}
+	return discordTimestamps(t)
+}
+
+// discordTimestamps lists the Discord timestamp markup of a time in each style,
+// which shows in the local time of whoever reads it, along with the raw markup to copy
+func discordTimestamps(t time.Time) string {
+	var reply strings.Builder
+	for _, s := range timestampStyles {
+		markup := fmt.Sprintf("<t:%d:%s>", t.Unix(), s.style)
+		fmt.Fprintf(&reply, "%s `%s` (%s)\n", markup, markup, s.name)
+	}
+	return strings.TrimSuffix(reply.String(), "\n")
+}
@@ -0,0 +1,260 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zones work without the system tz database
+
+	p "unit-bot/parser"
+)
+
+// zoneAbbreviations are the UTC offsets of common time zone abbreviations.
+// Abbreviations that name a region rather than standard or daylight time follow its rules instead
+var zoneAbbreviations = map[string]string{
+	"UTC": "UTC", "GMT": "+00:00", "Z": "UTC",
+	"ET": "America/New_York", "CT": "America/Chicago", "MT": "America/Denver", "PT": "America/Los_Angeles",
+	"EST": "-05:00", "EDT": "-04:00",
+	"CDT": "-05:00",
+	"MST": "-07:00", "MDT": "-06:00",
+	"PST": "-08:00", "PDT": "-07:00",
+	"AKST": "-09:00", "AKDT": "-08:00",
+	"HST": "-10:00",
+	"NST": "-03:30", "NDT": "-02:30",
+	"ADT": "-03:00",
+	"BRT": "-03:00", "ART": "-03:00",
+	"WET": "+00:00", "WEST": "+01:00",
+	"CET": "+01:00", "CEST": "+02:00",
+	"EET": "+02:00", "EEST": "+03:00",
+	"MSK": "+03:00",
+	"GST": "+04:00",
+	"PKT": "+05:00",
+	"NPT": "+05:45",
+	"ICT": "+07:00", "WIB": "+07:00",
+	"HKT": "+08:00", "SGT": "+08:00", "AWST": "+08:00", "PHT": "+08:00",
+	"JST": "+09:00", "KST": "+09:00",
+	"ACST": "+09:30", "ACDT": "+10:30",
+	"AEST": "+10:00", "AEDT": "+11:00",
+	"NZST": "+12:00", "NZDT": "+13:00",
+}
+
+// ambiguousZones are abbreviations used by more than one time zone, with the default first.
+// Guilds can pick another default with SetGuildZone
+var ambiguousZones = map[string][]string{
+	"IST": {"Asia/Kolkata", "Asia/Jerusalem", "Europe/Dublin"},
+	"CST": {"-06:00", "Asia/Shanghai", "America/Havana"},
+	"BST": {"+01:00", "Asia/Dhaka"},
+	"AST": {"-04:00", "Asia/Riyadh"},
+	"SST": {"Pacific/Pago_Pago", "Asia/Singapore"},
+}
+
+var offsetPattern = regexp.MustCompile(`^(?i:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// zoneKey is the setting key of the default time zone for an abbreviation
+func zoneKey(abbr string) string {
+	return "tz:" + strings.ToUpper(abbr)
+}
+
+// LookupZone finds a time zone by abbreviation, IANA name or UTC offset.
+// Ambiguous abbreviations use the default of the guild if it has one
+func LookupZone(scope Scope, name string) (*time.Location, error) {
+	if zone, ok := lookupSetting(scope, zoneKey(name)); ok {
+		return loadZone(strings.ToUpper(name), zone)
+	}
+	if zones, ok := ambiguousZones[strings.ToUpper(name)]; ok {
+		return loadZone(strings.ToUpper(name), zones[0])
+	}
+	if zone, ok := zoneAbbreviations[strings.ToUpper(name)]; ok {
+		return loadZone(strings.ToUpper(name), zone)
+	}
+	return loadZone(name, name)
+}
+
+// loadZone loads an IANA time zone or a fixed UTC offset, named by label
+func loadZone(label, zone string) (*time.Location, error) {
+	if m := offsetPattern.FindStringSubmatch(zone); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("Unknown time zone %s", label)
+		}
+		if label == zone {
+			label = fmt.Sprintf("UTC%s%02d:%02d", m[1], hours, minutes)
+		}
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(label, offset), nil
+	}
+	if loc, err := time.LoadLocation(zone); err == nil {
+		return loc, nil
+	}
+	return nil, fmt.Errorf("Unknown time zone %s", label)
+}
+
+// SetGuildZone sets which time zone an ambiguous abbreviation means in a guild, e.g. IST to Asia/Jerusalem.
+// An empty zone goes back to the default
+func SetGuildZone(guildID, abbr, zone string) error {
+	if zone != "" {
+		if _, err := loadZone(abbr, zone); err != nil {
+			return err
+		}
+	}
+	return setSetting(guildScope, guildID, zoneKey(abbr), zone)
+}
+
+// homeZoneKey is the setting key of the time zone relative dates like today are in
+const homeZoneKey = "tz"
+
+// homeZone is the time zone of the user, falling back to their guild's, or UTC if neither has one
+func homeZone(scope Scope) *time.Location {
+	if zone, ok := lookupSetting(scope, homeZoneKey); ok {
+		if loc, err := LookupZone(scope, zone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// SetUserHomeZone sets the time zone relative dates like today are in for a user, e.g. Europe/Paris.
+// An empty zone goes back to their guild's
+func SetUserHomeZone(userID, zone string) error {
+	if zone != "" {
+		if _, err := LookupZone(Scope{}, zone); err != nil {
+			return err
+		}
+	}
+	return setSetting(userScope, userID, homeZoneKey, zone)
+}
+
+// SetGuildHomeZone sets the time zone relative dates like today are in for a guild.
+// An empty zone goes back to UTC
+func SetGuildHomeZone(guildID, zone string) error {
+	if zone != "" {
+		if _, err := LookupZone(Scope{GuildID: guildID}, zone); err != nil {
+			return err
+		}
+	}
+	return setSetting(guildScope, guildID, homeZoneKey, zone)
+}
+
+// isZone checks whether a name is a time zone, or the Discord timestamp target, so times can be converted to other units
+func isZone(name string) bool {
+	if strings.EqualFold(name, "timestamp") {
+		return true
+	}
+	_, err := LookupZone(Scope{}, name)
+	return err == nil
+}
+
+// wallClock is a time of day as written, with an optional date
+type wallClock struct {
+	date      string
+	clock     map[string]string
+	now       bool
+	twelveHrs bool
+}
+
+// zoneCommand converts a time between time zones, e.g. 3pm EST to CET, or to Discord timestamps
+type zoneCommand struct {
+	at       wallClock
+	from, to string
+}
+
+var (
+	zoneToken  = p.Token(`([A-Za-z][A-Za-z0-9_/+:-]*|[+-]\d{1,2}(:?\d{2})?)`).Filter(notKeyword)
+	dateToken  = p.Token(`\d{4}-\d{2}-\d{2}`)
+	clock24    = p.Sub(`(?i)(?P<hour>\d{1,2}):(?P<min>\d{2})(:(?P<sec>\d{2}))?\s*(?P<ampm>[ap]\.?m\b\.?)?`)
+	clock12    = p.Sub(`(?i)(?P<hour>\d{1,2})\s*(?P<ampm>[ap]\.?m\b\.?)`)
+	clockWords = p.Map(p.Token(`(?i)(noon|midnight)`), mapClockWord)
+	clockExpr  = p.Parse2(dateToken.Or(""), p.First(clock24, clock12, clockWords), mapClock)
+	nowExpr    = p.Map(p.Token(`(?i)now`), func(string) wallClock { return wallClock{now: true} })
+	wallExpr   = p.First(nowExpr, clockExpr)
+	zoneTarget = p.Parse2(p.Atom(`to`), zoneToken.Filter(isZone), func(_ string, zone string) string { return zone })
+	zoneExpr   = p.Parse3(wallExpr, zoneToken.Or(""), zoneTarget, mapZone)
+)
+
+func mapClockWord(word string) map[string]string {
+	if strings.EqualFold(word, "noon") {
+		return map[string]string{"hour": "12", "ampm": "pm"}
+	}
+	return map[string]string{"hour": "12", "ampm": "am"}
+}
+
+func mapClock(date string, clock map[string]string) wallClock {
+	return wallClock{date: date, clock: clock, twelveHrs: clock["ampm"] != ""}
+}
+
+func mapZone(at wallClock, from string, to string) zoneCommand {
+	return zoneCommand{at, from, to}
+}
+
+// in resolves the wall clock time in a time zone, on today's date there if it has none
+func (w wallClock) in(loc *time.Location) (time.Time, error) {
+	now := time.Now().In(loc)
+	if w.now {
+		return now, nil
+	}
+
+	year, month, day := now.Date()
+	if w.date != "" {
+		d, err := time.Parse("2006-01-02", w.date)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid date %s", w.date)
+		}
+		year, month, day = d.Date()
+	}
+
+	hour, _ := strconv.Atoi(w.clock["hour"])
+	minute, _ := strconv.Atoi(w.clock["min"])
+	second, _ := strconv.Atoi(w.clock["sec"])
+	switch ampm := strings.ToLower(w.clock["ampm"]); {
+	case ampm == "":
+	case hour < 1 || hour > 12:
+		return time.Time{}, fmt.Errorf("Invalid time %d%s", hour, ampm)
+	case ampm[0] == 'p' && hour != 12:
+		hour += 12
+	case ampm[0] == 'a' && hour == 12:
+		hour = 0
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("Invalid time %02d:%02d", hour, minute)
+	}
+	return time.Date(year, month, day, hour, minute, second, 0, loc), nil
+}
+
+func (cmd zoneCommand) run(scope Scope) string {
+	t, err := zonedTime{cmd.at, cmd.from}.resolve(scope)
+	if err != nil {
+		return err.Error()
+	}
+	if strings.EqualFold(cmd.to, "timestamp") {
+		return discordTimestamps(t)
+	}
+	to, err := LookupZone(scope, cmd.to)
+	if err != nil {
+		return err.Error()
+	}
+
+	layout := "Mon Jan 2 15:04"
+	if cmd.at.twelveHrs {
+		layout = "Mon Jan 2 3:04 PM"
+	}
+	if cmd.at.now {
+		return fmt.Sprintf("It's %s", formatZoned(t.In(to), layout, cmd.to))
+	}
+	return fmt.Sprintf("%s = %s", formatZoned(t, layout, cmd.from), formatZoned(t.In(to), layout, cmd.to))
+}
+
+// formatZoned formats a time with the zone it was asked in, and its abbreviation if that differs
+func formatZoned(t time.Time, layout, asked string) string {
+	s := t.Format(layout) + " " + asked
+	abbr := t.Format("MST")
+	if !strings.EqualFold(abbr, asked) && !strings.HasPrefix(abbr, "UTC") && !strings.ContainsAny(abbr[:1], "+-") {
+		s += " (" + abbr + ")"
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTimeZones(t *testing.T) {
+	checkReplies(t, Scope{}, []replyTest{
+		{"2024-01-15 3pm EST to CET", "Mon Jan 15 3:00 PM EST = Mon Jan 15 9:00 PM CET"},
+		{"2024-07-01 15:30 America/New_York to Asia/Tokyo", "Mon Jul 1 15:30 America/New_York (EDT) = Tue Jul 2 04:30 Asia/Tokyo (JST)"},
+		{"2024-01-15 9:05 a.m. UTC to America/Los_Angeles", "Mon Jan 15 9:05 AM UTC = Mon Jan 15 1:05 AM America/Los_Angeles (PST)"},
+		{"2024-01-15 noon UTC to JST", "Mon Jan 15 12:00 PM UTC = Mon Jan 15 9:00 PM JST"},
+		{"3pm Nowhere/Land to CET", "Unknown time zone Nowhere/Land"},
+		{"13pm UTC to JST", "Invalid time 13pm"},
+	})
+
+	// Without a date these are today, so only the shape of the reply is checked
+	for _, tt := range []struct{ expr, prefix, suffix string }{
+		{"3pm EST to CET", "", "9:00 PM CET"},
+		// am in America isn't a time of day
+		{"15:30 America/New_York to Asia/Tokyo", "", "04:30 Asia/Tokyo (JST)"},
+		{"now to JST", "It's ", " JST"},
+	} {
+		got := ProcessIn(Scope{}, tt.expr)
+		if !strings.HasPrefix(got, tt.prefix) || !strings.HasSuffix(got, tt.suffix) {
+			t.Errorf("ProcessIn(%q) = %q, want %q...%q", tt.expr, got, tt.prefix, tt.suffix)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	got := Timestamp(Scope{}, "2024-01-15 15:30 America/New_York")
+	if !strings.HasPrefix(got, "<t:1705350600:t> `<t:1705350600:t>` (short time)\n") {
+		t.Errorf("Timestamp = %q, want <t:1705350600:t> first", got)
+	}
+}
@@ -0,0 +1,142 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+)
+
+// UncertainVal is a value with an absolute uncertainty in the same unit, e.g. 12.5 ± 0.2 mm
+type UncertainVal struct {
+	Val   UnitVal
+	Delta float64
+}
+
+// NewRelativeUncertainVal creates a value with an uncertainty relative to its magnitude, e.g. 3 kg ± 5%
+func NewRelativeUncertainVal(v UnitVal, rel float64) UncertainVal {
+	return UncertainVal{v, math.Abs(magnitude(v) * rel)}
+}
+
+// Relative returns the uncertainty relative to the magnitude of the value
+func (uv UncertainVal) Relative() float64 {
+	return uv.Delta / math.Abs(magnitude(uv.Val))
+}
+
+// Convert implements UnitVal conversion.
+// The uncertainty is converted as a difference, so it scales for units with a factor
+// and keeps its size for units with only an offset
+func (uv UncertainVal) Convert(to UnitType) (UnitVal, error) {
+	val, err := uv.Val.Convert(to)
+	if err != nil {
+		return nil, err
+	}
+	delta, err := convertDelta(uv.Val.Unit(), magnitude(uv.Val), uv.Delta, to)
+	if err != nil {
+		return nil, err
+	}
+	return UncertainVal{val, delta}, nil
+}
+
+// Add adds another value, combining the absolute uncertainties in quadrature
+func (uv UncertainVal) Add(other UnitVal) (UnitVal, error) {
+	return combine("add", uv, other, 1)
+}
+
+// Sub subtracts another value, combining the absolute uncertainties in quadrature
+func (uv UncertainVal) Sub(other UnitVal) (UnitVal, error) {
+	return combine("subtract", uv, other, -1)
+}
+
+// Mul scales the value by an exact factor, keeping its relative uncertainty
+func (uv UncertainVal) Mul(k float64) (UnitVal, error) {
+	return scale(uv, k), nil
+}
+
+// Div divides the value by an exact factor, keeping its relative uncertainty
+func (uv UncertainVal) Div(k float64) (UnitVal, error) {
+	return divide(uv, k)
+}
+
+func (uv UncertainVal) Neg() (UnitVal, error) {
+	return scale(uv, -1), nil
+}
+
+func (uv UncertainVal) Abs() (UnitVal, error) {
+	return scale(uv, math.Copysign(1, uv.float())), nil
+}
+
+// Cmp compares the central values, ignoring the uncertainty
+func (uv UncertainVal) Cmp(other UnitVal) (int, error) {
+	return compare(uv, other)
+}
+
+func (uv UncertainVal) Unit() UnitType {
+	return uv.Val.Unit()
+}
+
+func (uv UncertainVal) float() float64 {
+	return magnitude(uv.Val)
+}
+
+func (uv UncertainVal) String() string {
+	if _, ok := uv.Val.(FootInchVal); ok {
+		if feet, err := uv.Convert(Foot); err == nil {
+			return feet.String()
+		}
+	}
+	return fmt.Sprintf("%s %s", formatUncertain(magnitude(uv.Val), uv.Delta), uv.Unit())
+}
+
+// uncertainty returns the absolute uncertainty of a value, which is 0 for exact values
+func uncertainty(v UnitVal) float64 {
+	if uv, ok := v.(UncertainVal); ok {
+		return uv.Delta
+	}
+	return 0
+}
+
+// convertDelta converts a difference d at x in a unit to another unit
+func convertDelta(from UnitType, x, d float64, to UnitType) (float64, error) {
+	lo, err := from.FromFloat(x).Convert(to)
+	if err != nil {
+		return 0, err
+	}
+	hi, err := from.FromFloat(x + d).Convert(to)
+	if err != nil {
+		return 0, err
+	}
+	return math.Abs(magnitude(hi) - magnitude(lo)), nil
+}
+
+// formatUncertain formats a value and its uncertainty.
+// The uncertainty is rounded to one significant digit, or two if it starts with a 1,
+// and the value is rounded to the same decimal place.
+// Very small or large values share a power of ten, e.g. (1.20 ± 0.15)e-08
+func formatUncertain(v, d float64) string {
+	if d <= 0 || math.IsInf(d, 0) || math.IsNaN(d) {
+		return fmt.Sprintf("%.6g ± %.2g", v, d)
+	}
+
+	exp := math.Floor(math.Log10(d))
+	if d/math.Pow(10, exp) < 2 {
+		exp--
+	}
+	scale := math.Pow(10, exp)
+	d = math.Round(d/scale) * scale
+	v = math.Round(v/scale) * scale
+
+	if exp < -6 || exp > 12 {
+		shared := exp
+		if v != 0 {
+			shared = math.Floor(math.Log10(math.Abs(v)))
+		}
+		decimals := int(math.Max(shared-exp, 0))
+		power := math.Pow(10, shared)
+		return fmt.Sprintf("(%.*f ± %.*f)e%+03d", decimals, v/power, decimals, d/power, int(shared))
+	}
+
+	decimals := 0
+	if exp < 0 {
+		decimals = int(-exp)
+	}
+	return fmt.Sprintf("%.*f ± %.*f", decimals, v, decimals, d)
+}
@@ -3,25 +3,35 @@ package convert
 import (
 	"fmt"
 	"log/slog"
+	"math"
 	"strings"
 	"sync"
 )
 
 var (
-	unitAliasMap     map[string]UnitType
+	unitAliasMap map[string]UnitType
+	// unitSymbolMap are the case sensitive symbols of SI units, e.g. N, which isn't n
+	unitSymbolMap    map[string]UnitType
 	unitDimensionMap map[UnitDimension][]UnitType
 	unitLock         sync.RWMutex
 )
 
 func init() {
 	unitAliasMap = make(map[string]UnitType)
+	unitSymbolMap = make(map[string]UnitType)
 	unitDimensionMap = make(map[UnitDimension][]UnitType)
 	refreshUnitMaps()
 }
 
 func refreshUnitMaps() {
+	unitDimensionMap = make(map[UnitDimension][]UnitType)
 	for unit, aliases := range supportedUnits {
+		_, derived := unit.(*DerivedUnit)
 		for _, alias := range aliases {
+			if derived && alias != strings.ToLower(alias) {
+				unitSymbolMap[alias] = unit
+				continue
+			}
 			alias = strings.ToLower(alias)
 			if _, ok := unitAliasMap[alias]; !ok {
 				unitAliasMap[alias] = unit
@@ -30,21 +40,50 @@ func refreshUnitMaps() {
 		dim := unit.Dimension()
 		unitDimensionMap[dim] = append(unitDimensionMap[dim], unit)
 	}
+	// Constants are units by their symbols, unless they clash with other units
+	for symbol, u := range constantUnits {
+		if !prefixedConstants[symbol] {
+			unitSymbolMap[symbol] = u
+		}
+	}
 
 	slog.Info("Refreshed unit maps",
 		"unitAliasMap", unitAliasMap, "unitDimensionMap", unitDimensionMap)
 }
 
-// LookupUnit parses a UnitType.
+// LookupUnit parses a UnitType, with ambiguous currency symbols meaning their default currency.
 // Lazily loads currency units
 func LookupUnit(s string) (UnitType, bool) {
-	s = strings.ToLower(s)
+	if symbol, ok := ambiguousSymbol(s); ok {
+		s = ambiguousSymbols[symbol][0]
+	}
+	if u, ok := lookupNamedUnit(s); ok {
+		return u, true
+	}
+	if u, ok := parseCompoundUnit(s); ok {
+		return u, true
+	}
+	return nil, false
+}
+
+// lookupNamedUnit finds a unit by its name or one of its aliases, or a constant by its symbol, e.g. g0 or const:G.
+// Symbols of SI units with capitals and of constants are case sensitive and are matched first
+func lookupNamedUnit(s string) (UnitType, bool) {
+	if strings.HasPrefix(s, constantPrefix) {
+		u, ok := constantUnits[strings.TrimPrefix(s, constantPrefix)]
+		return u, ok
+	}
+
 	unitLock.RLock()
 	defer unitLock.RUnlock()
+	if u, ok := unitSymbolMap[s]; ok {
+		return u, true
+	}
+	s = strings.ToLower(s)
 	u, ok := unitAliasMap[s]
 	if !ok {
 		unitLock.RUnlock()
-		currencyOnce.Do(loadCurrencies)
+		awaitCurrencies()
 		unitLock.RLock()
 		u, ok = unitAliasMap[s]
 	}
@@ -62,8 +101,52 @@ const (
 	UnitDimensionTemperature
 	UnitDimensionVolume
 	UnitDimensionCurrency
+	UnitDimensionDate
+	UnitDimensionForce
+	UnitDimensionEnergy
+	UnitDimensionPower
+	UnitDimensionPressure
+	UnitDimensionFrequency
+	UnitDimensionCurrent
+	UnitDimensionAmount
+	UnitDimensionLuminousIntensity
 )
 
+var dimensionNames = map[UnitDimension]string{
+	UnitDimensionNone:        "other",
+	UnitDimensionLength:      "length",
+	UnitDimensionMass:        "mass",
+	UnitDimensionSpeed:       "speed",
+	UnitDimensionDuration:    "duration",
+	UnitDimensionTemperature: "temperature",
+	UnitDimensionVolume:      "volume",
+	UnitDimensionCurrency:    "currency",
+	UnitDimensionDate:        "date",
+
+	UnitDimensionForce:             "force",
+	UnitDimensionEnergy:            "energy",
+	UnitDimensionPower:             "power",
+	UnitDimensionPressure:          "pressure",
+	UnitDimensionFrequency:         "frequency",
+	UnitDimensionCurrent:           "current",
+	UnitDimensionAmount:            "amount",
+	UnitDimensionLuminousIntensity: "luminous intensity",
+}
+
+func (d UnitDimension) String() string {
+	return dimensionNames[d]
+}
+
+// LookupDimension finds a UnitDimension by its name
+func LookupDimension(s string) (UnitDimension, bool) {
+	for dim, name := range dimensionNames {
+		if strings.EqualFold(name, s) {
+			return dim, true
+		}
+	}
+	return UnitDimensionNone, false
+}
+
 // UnitType represent a single type of unit
 type UnitType interface {
 	fmt.Stringer
@@ -71,11 +154,21 @@ type UnitType interface {
 	FromFloat(float64) UnitVal
 }
 
-// UnitVal is a value with unit that can be converted to another unit
+// UnitVal is a value with unit that can be converted to another unit.
+// Arithmetic with another value converts it to the unit of the receiver first.
+// Every arithmetic method returns an error, for operations with no meaning for the value,
+// e.g. multiplying a date, or that fail, e.g. dividing by zero
 type UnitVal interface {
 	fmt.Stringer
 	Unit() UnitType
 	Convert(to UnitType) (UnitVal, error)
+	Add(other UnitVal) (UnitVal, error)
+	Sub(other UnitVal) (UnitVal, error)
+	Mul(k float64) (UnitVal, error)
+	Div(k float64) (UnitVal, error)
+	Neg() (UnitVal, error)
+	Abs() (UnitVal, error)
+	Cmp(other UnitVal) (int, error)
 }
 
 // ErrorConversion occurs when a UnitType cannot be converted to another UnitType
@@ -87,6 +180,26 @@ func (err ErrorConversion) Error() string {
 	return fmt.Sprintf("Can't convert from %s to %s", err.From.String(), err.To.String())
 }
 
+// ErrorInvalidUnit occurs when a unit can't be found
+type ErrorInvalidUnit struct {
+	Unit string
+}
+
+func (err ErrorInvalidUnit) Error() string {
+	if currenciesUnavailable() && mightBeCurrency(err.Unit) {
+		return fmt.Sprintf("Invalid unit %s. If it's a currency, currencies are temporarily unavailable, try again later", err.Unit)
+	}
+	return fmt.Sprintf("Invalid unit %s", err.Unit)
+}
+
+// magnitude returns the numeric part of a value in its own unit
+func magnitude(v UnitVal) float64 {
+	if s, ok := v.(interface{ float() float64 }); ok {
+		return s.float()
+	}
+	return math.NaN()
+}
+
 func simpleUnitString(f float64, u UnitType) string {
 	return fmt.Sprintf("%.6g %s", f, u.String())
 }
@@ -97,6 +210,12 @@ func from[U ~float64](base U) func(float64) U {
 	}
 }
 
+func inUnitsOf[U ~float64](base U) func(U) float64 {
+	return func(v U) float64 {
+		return float64(v / base)
+	}
+}
+
 type SimpleUnit[U ~float64] struct {
 	dimension UnitDimension
 	name      string
@@ -115,6 +234,12 @@ func (u *SimpleUnit[U]) Dimension() UnitDimension {
 	return u.dimension
 }
 
+func (u *SimpleUnit[U]) si() (siDef, bool) {
+	dims, ok := dimensionDims[u.dimension]
+	offset := float64(u.fromFloat(0))
+	return siDef{float64(u.fromFloat(1)) - offset, offset, dims}, ok
+}
+
 func (u *SimpleUnit[U]) String() string {
 	return u.name
 }
@@ -129,13 +254,45 @@ func (v SimpleUnitValue[U]) Convert(to UnitType) (UnitVal, error) {
 		v.unit = to
 		return v, nil
 	}
-	return nil, ErrorConversion{v.unit, to}
+	return convertSI(v, to)
 }
 
 func (v SimpleUnitValue[U]) Unit() UnitType {
 	return v.unit
 }
 
+func (v SimpleUnitValue[U]) Add(other UnitVal) (UnitVal, error) {
+	return combine("add", v, other, 1)
+}
+
+func (v SimpleUnitValue[U]) Sub(other UnitVal) (UnitVal, error) {
+	return combine("subtract", v, other, -1)
+}
+
+func (v SimpleUnitValue[U]) Mul(k float64) (UnitVal, error) {
+	return scale(v, k), nil
+}
+
+func (v SimpleUnitValue[U]) Div(k float64) (UnitVal, error) {
+	return divide(v, k)
+}
+
+func (v SimpleUnitValue[U]) Neg() (UnitVal, error) {
+	return scale(v, -1), nil
+}
+
+func (v SimpleUnitValue[U]) Abs() (UnitVal, error) {
+	return scale(v, math.Copysign(1, v.float())), nil
+}
+
+func (v SimpleUnitValue[U]) Cmp(other UnitVal) (int, error) {
+	return compare(v, other)
+}
+
+func (v SimpleUnitValue[U]) float() float64 {
+	return v.unit.toFloat(v.value)
+}
+
 func (v SimpleUnitValue[U]) String() string {
 	return simpleUnitString(v.unit.toFloat(v.value), v.unit)
 }
@@ -0,0 +1,202 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// unitsPerPage is how many units are listed at once, to fit in a Discord message
+	unitsPerPage = 20
+	// unitSamples is how many sample conversions are shown for a unit
+	unitSamples = 3
+)
+
+// Units lists units for the !units command, e.g. !units length 2 or !units luminous intensity
+func Units(args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return UnitsPage("", 1)
+	}
+	page := 1
+	if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil && len(fields) > 1 {
+		page = n
+		fields = fields[:len(fields)-1]
+	}
+	return UnitsPage(strings.Join(fields, " "), page)
+}
+
+// UnitsPage lists a page of the units of a dimension,
+// or gives an overview of all of the dimensions if none is given
+func UnitsPage(dimension string, page int) string {
+	if dimension == "" {
+		return unitsOverview()
+	}
+
+	dim, ok := LookupDimension(dimension)
+	if !ok {
+		return fmt.Sprintf("Unknown dimension %s\n%s", dimension, unitsOverview())
+	}
+	if dim == UnitDimensionCurrency {
+		awaitCurrencies()
+	}
+
+	units := unitsOf(dim)
+	pages := (len(units) + unitsPerPage - 1) / unitsPerPage
+	if pages == 0 {
+		if dim == UnitDimensionCurrency && currenciesUnavailable() {
+			state, _ := CurrencyStatus()
+			return fmt.Sprintf("Currencies are %s, try again later", state)
+		}
+		return fmt.Sprintf("No %s units are available right now", dim)
+	}
+	if page < 1 {
+		page = 1
+	} else if page > pages {
+		page = pages
+	}
+
+	var reply strings.Builder
+	fmt.Fprintf(&reply, "**%s units** (page %d/%d)\n", dim, page, pages)
+	end := page * unitsPerPage
+	if end > len(units) {
+		end = len(units)
+	}
+	for _, u := range units[(page-1)*unitsPerPage : end] {
+		reply.WriteString(u.String())
+		if aliases := otherAliases(u); len(aliases) > 0 {
+			fmt.Fprintf(&reply, " (%s)", strings.Join(aliases, ", "))
+		}
+		reply.WriteString("\n")
+	}
+	if page < pages {
+		fmt.Fprintf(&reply, "Use !units %s %d for more", dim, page+1)
+	}
+	return strings.TrimSuffix(reply.String(), "\n")
+}
+
+func unitsOverview() string {
+	unitLock.RLock()
+	defer unitLock.RUnlock()
+
+	var dims []string
+	for dim := UnitDimensionNone; dim <= UnitDimensionLuminousIntensity; dim++ {
+		if n := len(unitDimensionMap[dim]); n > 0 {
+			dims = append(dims, fmt.Sprintf("%s (%d)", dim, n))
+		}
+	}
+	return "Dimensions: " + strings.Join(dims, ", ") +
+		"\nUse !units [dimension] to list units, or !unit [name] for details"
+}
+
+// unitsOf lists the units of a dimension from smallest to largest
+func unitsOf(dim UnitDimension) []UnitType {
+	unitLock.RLock()
+	units := append([]UnitType(nil), unitDimensionMap[dim]...)
+	unitLock.RUnlock()
+
+	sort.Slice(units, func(i, j int) bool {
+		a, okA := siDefOf(units[i])
+		b, okB := siDefOf(units[j])
+		if okA && okB && a.Factor != b.Factor {
+			return a.Factor < b.Factor
+		}
+		return units[i].String() < units[j].String()
+	})
+	return units
+}
+
+// otherAliases lists the aliases of a unit other than its name
+func otherAliases(u UnitType) []string {
+	unitLock.RLock()
+	defer unitLock.RUnlock()
+
+	var aliases []string
+	for _, alias := range supportedUnits[u] {
+		if alias != u.String() {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// UnitInfo describes a unit for the !unit command, e.g. !unit furlong
+func UnitInfo(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "Usage: !unit [name]"
+	}
+	u, ok := LookupUnit(name)
+	if !ok {
+		return ErrorInvalidUnit{name}.Error()
+	}
+
+	var info strings.Builder
+	fmt.Fprintf(&info, "**%s** (%s)", u, u.Dimension())
+	if aliases := otherAliases(u); len(aliases) > 0 {
+		fmt.Fprintf(&info, "\nAliases: %s", strings.Join(aliases, ", "))
+	}
+
+	def, ok := siDefOf(u)
+	if !ok {
+		if u.Dimension() == UnitDimensionCurrency {
+			info.WriteString("\nConverted at current exchange rates")
+		}
+		return info.String()
+	}
+	fmt.Fprintf(&info, "\nDefinition: %s", describeSI(u, def))
+
+	for _, sample := range sampleConversions(u, def) {
+		fmt.Fprintf(&info, "\n%s = %s", u.FromFloat(1), sample)
+	}
+	return info.String()
+}
+
+// describeSI writes the definition of a unit in SI base units, e.g. 1 ft = 0.3048 m
+func describeSI(u UnitType, def siDef) string {
+	base := def.Dims.String()
+	switch {
+	case def.Offset == 0:
+		return fmt.Sprintf("1 %s = %.10g %s", u, def.Factor, base)
+	case def.Factor == 1:
+		return fmt.Sprintf("x %s = (x + %.10g) %s", u, def.Offset, base)
+	default:
+		return fmt.Sprintf("x %s = (%.10gx + %.10g) %s", u, def.Factor, def.Offset, base)
+	}
+}
+
+// sampleConversions converts 1 of a unit to the units of its dimension closest in size
+func sampleConversions(u UnitType, def siDef) []UnitVal {
+	type candidate struct {
+		val      UnitVal
+		distance float64
+	}
+	var candidates []candidate
+	for _, other := range unitsOf(u.Dimension()) {
+		if other == u || other == UnitType(FootInch) {
+			continue
+		}
+		otherDef, ok := siDefOf(other)
+		if !ok {
+			continue
+		}
+		val, err := u.FromFloat(1).Convert(other)
+		if err != nil || val.String() == u.FromFloat(1).String() {
+			continue
+		}
+		distance := math.Abs(math.Log(def.Factor / otherDef.Factor))
+		candidates = append(candidates, candidate{val, distance})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	var samples []UnitVal
+	for i := 0; i < len(candidates) && i < unitSamples; i++ {
+		samples = append(samples, candidates[i].val)
+	}
+	return samples
+}